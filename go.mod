@@ -2,6 +2,8 @@ module zincsearch.com/mailindex/api
 
 go 1.19
 
+require golang.org/x/text v0.5.0
+
 require (
 	github.com/chzyer/readline v1.5.1 // indirect
 	github.com/google/pprof v0.0.0-20221212185716-aee1124e3a93 // indirect
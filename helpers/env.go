@@ -0,0 +1,67 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lee boolean de variable de ambiente: true, 1 o s
+func GetEnvBool(name string) bool {
+	txt := strings.ToLower(os.Getenv(name))
+	return txt == "true" || txt == "1" || txt == "s"
+}
+
+// lee entero no negativo de variable de ambiente, si no esta definida retorna def
+func GetEnvInt(name string, def int) (int, error) {
+	txt := os.Getenv(name)
+	if txt == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(txt)
+	if err != nil || n < 0 {
+		return def, fmt.Errorf("el valor definido para %s debe ser numerico. Valor recibido: %q", name, txt)
+	}
+	return n, nil
+}
+
+// lee duracion de variable de ambiente, acepta formato Go (90s, 2m) o segundos enteros
+func GetEnvDuration(name string, def time.Duration) (time.Duration, error) {
+	txt := os.Getenv(name)
+	if txt == "" {
+		return def, nil
+	}
+	if seconds, err := strconv.Atoi(txt); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	d, err := time.ParseDuration(txt)
+	if err != nil {
+		return def, fmt.Errorf("valor invalido para %s: %q", name, txt)
+	}
+	return d, nil
+}
+
+// lee tamaño en bytes de variable de ambiente, acepta sufijos KB, MB y GB (base 1024)
+func GetEnvBytes(name string, def int) (int, error) {
+	txt := strings.ToUpper(strings.TrimSpace(os.Getenv(name)))
+	if txt == "" {
+		return def, nil
+	}
+
+	multiplier := 1
+	for suffix, m := range map[string]int{"KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30} {
+		if strings.HasSuffix(txt, suffix) {
+			multiplier = m
+			txt = strings.TrimSpace(strings.TrimSuffix(txt, suffix))
+			break
+		}
+	}
+
+	n, err := strconv.Atoi(txt)
+	if err != nil || n < 0 {
+		return def, fmt.Errorf("valor invalido para %s: %q", name, os.Getenv(name))
+	}
+	return n * multiplier, nil
+}
@@ -3,8 +3,8 @@ package helpers
 import (
 	"encoding/json"
 	"io"
-	"log"
 	"net/http"
+	"strings"
 )
 
 type ErrorResponse struct {
@@ -31,23 +31,22 @@ func GetResponseBytes(response *http.Response) (str []byte, err error) {
 	return body, nil
 }
 
+// obtiene error de una respuesta HTTP no exitosa
 func GetError(response *http.Response) (httpError ErrorResponse, err error) {
 	body, err := io.ReadAll(response.Body)
 	if err != nil {
 		return httpError, err
 	}
-	err = json.Unmarshal(body, &httpError)
-	if err != nil {
-		log.Fatal(err)
-	}
-	httpError.Code = response.StatusCode
-
-	return httpError, err
-
+	return GetErrorFromBody(response.StatusCode, body), nil
 }
 
-func GetErrorResponse(code int, description string) (err ErrorResponse) {
-	err.Code = code
-	err.Error = description
-	return err
+// obtiene error a partir del cuerpo de la respuesta, ZincSearch responde {"error": "..."};
+// si el cuerpo no es JSON (ej. pagina HTML de un proxy) se utiliza como texto
+func GetErrorFromBody(code int, body []byte) (httpError ErrorResponse) {
+	err := json.Unmarshal(body, &httpError)
+	if err != nil || httpError.Error == "" {
+		httpError.Error = strings.TrimSpace(string(body))
+	}
+	httpError.Code = code
+	return httpError
 }
@@ -2,20 +2,29 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"net/mail"
+	"net/textproto"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"runtime/pprof"
+	"sort"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 
 	_ "net/http/pprof"
 
+	"zincsearch.com/mailindex/api/ingest"
+	"zincsearch.com/mailindex/api/mailparse"
 	"zincsearch.com/mailindex/api/override/godotenv"
 	"zincsearch.com/mailindex/api/service"
 )
@@ -23,16 +32,20 @@ import (
 var api service.ZincSearch
 var profiling bool = false
 var createMainIndex bool = false
-var queueMsgQuantity int = 0
 
-var archivos int = 0
-var carpetas int = 0
+// formato del resumen final: text o json
+var statsFormat string = "text"
 
-// cola utilizada para acumular documentos por enviar
-var queue chan string = make(chan string)
+// modo de generacion de id de documento, vacio utiliza id generado por el servidor
+var docIdMode string = ingest.DocIDServer
 
-// Crea grupos de espera para trabajos en paralelo
-var wg sync.WaitGroup
+// almacen de adjuntos, nil si no se configura ZINC_LOCAL_ATTACHMENT_STORE
+var attachmentStore *mailparse.AttachmentStore
+
+// headers adicionales a capturar y si se guardan en el objeto Headers (object)
+// o como campos del documento (fields)
+var headerCapture mailparse.HeaderCapture
+var headersMode string = "object"
 
 func init() {
 	err := godotenv.Load()
@@ -48,6 +61,30 @@ func init() {
 	createMainIndex = createIndex != "" && (strings.ToLower(createIndex) == "true" || createIndex == "1")
 	fmt.Println("create main index: ", createIndex)
 
+	if format := strings.ToLower(os.Getenv("ZINC_LOCAL_STATS_FORMAT")); format != "" {
+		statsFormat = format
+	}
+
+	docIdMode, err = ingest.DocIDModeFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if dir := os.Getenv("ZINC_LOCAL_ATTACHMENT_STORE"); dir != "" {
+		attachmentStore, err = mailparse.NewAttachmentStore(dir)
+		if err != nil {
+			log.Fatal("Error al crear almacen de adjuntos: ", err)
+		}
+	}
+
+	headerCapture = mailparse.ParseHeaderCapture(os.Getenv("ZINC_LOCAL_HEADERS"))
+	if mode := strings.ToLower(os.Getenv("ZINC_LOCAL_HEADERS_MODE")); mode != "" {
+		if mode != "object" && mode != "fields" {
+			log.Fatalf("valor invalido para ZINC_LOCAL_HEADERS_MODE: %q, se espera \"object\" o \"fields\"", mode)
+		}
+		headersMode = mode
+	}
+
 }
 
 func main() {
@@ -74,35 +111,124 @@ func main() {
 
 	fmt.Println("Inicia", time.Now().Format(time.RFC1123))
 
-	if len(os.Args) == 1 {
+	//--resume omite los archivos confirmados en una ejecucion anterior
+	resume := flag.Bool("resume", false, "continua una carga interrumpida, omitiendo los archivos ya confirmados por ZincSearch")
+	//--retry-dead-letters procesa nuevamente los archivos fallidos de la ejecucion anterior
+	retryDeadLetters := flag.Bool("retry-dead-letters", false, "procesa nuevamente los archivos registrados en el archivo de dead letters")
+	flag.Parse()
+
+	//flag.Parse se detiene en el primer argumento que no es flag: en
+	//"indexer /data/enron --resume" se ignoraria --resume y se borraria el checkpoint
+	if flag.NArg() > 1 {
+		log.Fatalf("Argumentos no reconocidos: %v. Las opciones deben ir antes de la ruta del directorio. Ej. indexer --resume C:\\enron_mail_20110402", flag.Args()[1:])
+	}
+
+	if flag.NArg() == 0 && !*retryDeadLetters {
 		log.Fatal("Es obligatorio ingresar la ruta del directorio. Ej. C:\\enron_mail_20110402")
 	}
 
-	var dirname = os.Args[1]
+	var dirname = flag.Arg(0)
+
+	//contexto cancelado con Ctrl-C o SIGTERM, detiene peticiones en curso
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	//inicializa servicio, cargando su configuracion del archivo .env
 	api.Inicia()
-	verificaIndice()
+	verificaIndice(ctx)
+
+	var stats *ingest.IngestStats
+	var err error
+	if *retryDeadLetters {
+		stats, err = reintentaDeadLetters(ctx)
+	} else {
+		stats, err = cargaDirectorio(ctx, dirname, *resume)
+	}
 
-	wg.Add(1)
-	go importaArchivos(dirname)
+	imprimeResumen(stats)
 
-	go func(wg *sync.WaitGroup) {
-		wg.Wait()
-		close(queue)
-	}(&wg)
+	//la carga fue cancelada (Ctrl-C, SIGTERM), se detiene sin enviar mas lotes
+	if ctx.Err() != nil {
+		log.Fatal("Carga cancelada: ", ctx.Err())
+	}
+	if err != nil {
+		log.Fatal("Error en envio de documentos: ", err)
+	}
 
-	enviarDocsAZincSearch()
+	fmt.Println("Termina", time.Now().Format(time.RFC1123))
+}
 
-	fmt.Println(" Folders procesados: ", carpetas)
-	fmt.Println(" Archivos procesados: ", archivos)
-	fmt.Println(" Mensajes procesados: ", queueMsgQuantity)
+// carga todos los archivos del directorio, registrando los fallidos como dead letters
+func cargaDirectorio(ctx context.Context, dirname string, resume bool) (*ingest.IngestStats, error) {
+	//archivos confirmados por ZincSearch, permite continuar una carga interrumpida
+	checkpoint, err := ingest.OpenCheckpoint(ingest.CheckpointFileFromEnv(), resume)
+	if err != nil {
+		log.Fatal("Error al abrir checkpoint: ", err)
+	}
+	defer checkpoint.Close()
+	if resume {
+		fmt.Println(" Archivos confirmados previamente: ", checkpoint.Len())
+	}
 
-	fmt.Println("Termina", time.Now().Format(time.RFC1123))
+	//archivos fallidos, al continuar una carga se agregan a los existentes
+	deadLetters, err := ingest.OpenDeadLetters(ingest.DeadLetterFileFromEnv(), resume)
+	if err != nil {
+		log.Fatal("Error al abrir archivo de dead letters: ", err)
+	}
+	defer deadLetters.Close()
+
+	return importaArchivos(ctx, ingest.RunConfig{
+		Root:        dirname,
+		Checkpoint:  checkpoint,
+		DeadLetters: deadLetters,
+	})
+}
+
+// procesa nuevamente los archivos del archivo de dead letters. Los que vuelven a
+// fallar se registran en un archivo nuevo que reemplaza al anterior solo si el
+// reintento termina; si se interrumpe, el archivo anterior se conserva
+func reintentaDeadLetters(ctx context.Context) (*ingest.IngestStats, error) {
+	path := ingest.DeadLetterFileFromEnv()
+
+	letters, err := ingest.ReadDeadLetters(path)
+	if err != nil {
+		log.Fatal("Error al leer archivo de dead letters: ", err)
+	}
+
+	//un archivo puede registrarse mas de una vez
+	files := make([]string, 0, len(letters))
+	vistos := map[string]bool{}
+	for _, letter := range letters {
+		if !vistos[letter.Path] {
+			vistos[letter.Path] = true
+			files = append(files, letter.Path)
+		}
+	}
+	fmt.Println(" Archivos a reintentar: ", len(files))
+
+	tmpPath := path + ".retry"
+	deadLetters, err := ingest.OpenDeadLetters(tmpPath, false)
+	if err != nil {
+		log.Fatal("Error al abrir archivo de dead letters: ", err)
+	}
+
+	stats, err := importaArchivos(ctx, ingest.RunConfig{
+		Files:       files,
+		DeadLetters: deadLetters,
+	})
+
+	if errClose := deadLetters.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil || ctx.Err() != nil {
+		os.Remove(tmpPath)
+		return stats, err
+	}
+	return stats, os.Rename(tmpPath, path)
 }
 
 // Veririca la existencia de indice, y en caso de no existir lo crea
-func verificaIndice() {
+func verificaIndice(ctx context.Context) {
 	if !createMainIndex {
 		return
 	}
@@ -110,71 +236,73 @@ func verificaIndice() {
 	//api.DeleteIndex(service.INDEX_NAME)
 
 	//busca existencia de indice
-	resultadoCreacion, _ := api.ExistsIndex(service.INDEX_NAME)
+	resultadoCreacion, err := api.ExistsIndex(ctx, service.INDEX_NAME)
+	if err != nil {
+		log.Fatal("No se pudo verificar el indice: ", err)
+	}
 	if !resultadoCreacion {
-		crearIndice()
+		crearIndice(ctx)
 	}
 
-	_, httpError := api.ExistsIndex(service.INDEX_NAME)
-	if httpError.Code != 0 {
-		log.Fatal("No se creó el indice, no se puede continuar")
+	existe, err := api.ExistsIndex(ctx, service.INDEX_NAME)
+	if err != nil || !existe {
+		log.Fatal("No se creó el indice, no se puede continuar: ", err)
 	}
 }
 
-// funcion llamada para iniciar procesamiento de archivos de correo
-func importaArchivos(dir string) (folders int, documents int) {
-	defer wg.Done()
-
-	//obtiene listado de archivos en directorio indicado
-	files, err := ioutil.ReadDir(dir)
-
+// procesa archivos de correo con un pool fijo de workers y envia los documentos
+// en lotes a ZincSearch con varios workers. cfg indica los archivos a procesar
+func importaArchivos(ctx context.Context, cfg ingest.RunConfig) (*ingest.IngestStats, error) {
+	var err error
+	cfg.ParserWorkers, err = ingest.ParserWorkersFromEnv()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	//almacenara subfolders encontrados
-	var subfolders []string
-
-	//recorrer listado de archivos y subfolders
-	for _, file := range files {
-
-		if file.IsDir() {
-
-			//utiliza separador del sistema operativo
-			path := filepath.Join(dir, file.Name())
-
-			//acumula cantidad de carpetas
-			carpetas++
-
-			//almacena lista de subfolders en slice para su procesamiento posterior
-			subfolders = append(subfolders, path)
-
-		} else {
-			archivos++
-
-			filename := filepath.Join(dir, file.Name())
-			// procesa en paralelo archivos
-			procesaArchivo(filename)
+	//limites de lote: documentos, bytes y antiguedad
+	cfg.Batch, err = ingest.BatchConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		}
+	//workers de envio y lotes en vuelo
+	cfg.Sender, err = ingest.SenderConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	if len(subfolders) > 0 {
-		for _, p := range subfolders {
-			wg.Add(1)
-			go importaArchivos(p)
+	cfg.Parse = procesaArchivo
+	cfg.Send = enviarDocs
+	//reintentos realizados por el servicio durante la carga
+	cfg.Retries = func() int64 { return api.RetryStats().Retries }
+	return ingest.Run(ctx, cfg)
+}
+
+// imprime resumen de la carga en el formato configurado
+func imprimeResumen(stats *ingest.IngestStats) {
+	if statsFormat == "json" {
+		jsonStats, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			log.Fatal(err)
 		}
+		fmt.Println(string(jsonStats))
+		return
 	}
-
-	return carpetas, archivos
+	fmt.Print(stats)
 }
 
-// Procesa envío de archivo individual
-func procesaArchivo(filename string) {
+// Procesa archivo individual, convirtiendolo en documento JSON
+func procesaArchivo(filename string) (doc ingest.Document, err error) {
 
 	dat, err := os.ReadFile(filename)
 	if err != nil {
-		log.Fatal(err)
+		return doc, ingest.NewFileError(ingest.StageRead, err)
+	}
+
+	//fecha de modificacion, ultima alternativa para la fecha del correo
+	var modTime time.Time
+	if info, err := os.Stat(filename); err == nil {
+		modTime = info.ModTime()
 	}
 
 	//parseo de texto a estructura email (headers/body)
@@ -182,21 +310,27 @@ func procesaArchivo(filename string) {
 
 	//Si no se pudo obtener la estructura del mail, se omite el registro
 	if err != nil {
-		return
+		return doc, ingest.NewFileError(ingest.StageParse, err)
 	}
 
-	stmail, err := parsearDatosEmail(msg)
+	stmail, err := parsearDatosEmail(msg, modTime)
 
 	if err != nil {
-		log.Fatal(err)
+		return doc, err
 	}
 
-	//envia JSON a canal
-	queue <- stmail
+	doc = ingest.Document{Source: filename, Body: stmail}
+
+	//id deterministico, permite repetir la carga sin duplicar documentos.
+	//Se normaliza igual que el campo MessageID del documento
+	messageID := mailparse.NormalizeMessageID(msg.Header.Get("Message-ID"))
+	doc.ID = ingest.DocumentID(docIdMode, messageID, dat)
+
+	return doc, nil
 }
 
 // crea indice como primer paso del proceso (cuando no existe)
-func crearIndice() {
+func crearIndice(ctx context.Context) {
 
 	if !createMainIndex {
 		return
@@ -213,109 +347,217 @@ func crearIndice() {
 		log.Fatal(err)
 	}
 
-	result, errorHttp := api.SaveIndex(service.INDEX_NAME, string(content))
+	_, err = api.SaveIndex(ctx, service.INDEX_NAME, string(content))
 
-	if result == "" {
-		log.Fatal("Error en creación de indice: ", errorHttp)
+	if err != nil {
+		log.Fatal("Error en creación de indice: ", err)
 	}
 
 }
 
-func enviarDocsAZincSearch() {
-	const MAX_POR_LOTE int = 5000 //debe ser multiplo de 1000
-	const COMMA byte = ','
-	const REQUEST_BEGIN string = "{\"index\": \"" + service.INDEX_NAME + "\",\"records\": ["
-	var sb strings.Builder
-	var i int = 0
-
-	for strJson := range queue {
-		if i > 0 {
-			sb.WriteByte(COMMA)
-		} else {
-			//prepara peticion
-			sb.WriteString(REQUEST_BEGIN)
-		}
-
-		//aumenta contador
-		i++
-
-		//agrega json actual a peticion
-		sb.WriteString(strJson)
-
-		queueMsgQuantity++
-		if queueMsgQuantity%MAX_POR_LOTE == 0 {
-			enviarDocs(&sb)
-
-			//reinicia datos para siguiente bloque
-			i = 0
+// envia lote a ZincSearch, el JSON se escribe directamente en la conexion.
+// Los documentos con id se envian con _bulk y accion index (crea o reemplaza)
+func enviarDocs(ctx context.Context, lote ingest.Batch) ([]ingest.Rejection, error) {
+	if docIdMode != ingest.DocIDServer {
+		resp, err := api.BulkStream(ctx, func(w io.Writer) error {
+			encoder := service.NewBulkEncoder(w, service.INDEX_NAME)
+			for _, doc := range lote.Docs {
+				op := service.BulkOperation{Action: service.BulkActionIndex, ID: doc.ID, Doc: json.RawMessage(doc.Body)}
+				if err := encoder.Encode(op); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return loteRechazado(lote, err)
 		}
+		return documentosRechazados(lote, resp), nil
 	}
 
-	//el ultimo lote puede no haber alcanzado el tamaño maximo
-	//por lo que se procesa si hay al menos un registro incluido
-	if i > 0 {
-		enviarDocs(&sb)
+	_, err := api.CreateDocumentBulkStream(ctx, func(w io.Writer) error {
+		encoder := service.NewBulkV2Encoder(w, service.INDEX_NAME)
+		for _, doc := range lote.Docs {
+			if err := encoder.EncodeRaw(doc.Body); err != nil {
+				return err
+			}
+		}
+		return encoder.Close()
+	})
+	if err != nil {
+		return loteRechazado(lote, err)
 	}
+	return nil, nil
 }
 
-func enviarDocs(sb *strings.Builder) {
-
-	const REQUEST_END string = "]}"
-	//cierra estructura JSON
-	sb.WriteString(REQUEST_END)
-	defer sb.Reset()
+// si el servidor rechaza el contenido del lote (400, 413) todos sus documentos se
+// registran como rechazados y la carga continua; cualquier otro error la detiene
+func loteRechazado(lote ingest.Batch, err error) ([]ingest.Rejection, error) {
+	var apiErr *service.APIError
+	if !errors.As(err, &apiErr) || (apiErr.StatusCode != http.StatusBadRequest && apiErr.StatusCode != http.StatusRequestEntityTooLarge) {
+		return nil, err
+	}
 
-	result, errorHttp := api.CreateDocumentBulk(sb.String())
+	rechazados := make([]ingest.Rejection, len(lote.Docs))
+	for i, doc := range lote.Docs {
+		rechazados[i] = ingest.Rejection{Source: doc.Source, Err: err}
+	}
+	return rechazados, nil
+}
 
-	if result == "" {
-		log.Fatal("Error en creación de indice: ", errorHttp)
+// documentos con error en la respuesta de _bulk, los items siguen el orden del lote
+func documentosRechazados(lote ingest.Batch, resp *service.BulkResponse) (rechazados []ingest.Rejection) {
+	for i, item := range resp.Items {
+		if i >= len(lote.Docs) {
+			break
+		}
+		for _, result := range item {
+			if result.Error == nil && result.Status <= 299 {
+				continue
+			}
+			err := fmt.Errorf("status %d", result.Status)
+			if result.Error != nil {
+				err = fmt.Errorf("status %d: %w", result.Status, result.Error)
+			}
+			rechazados = append(rechazados, ingest.Rejection{Source: lote.Docs[i].Source, Err: err})
+		}
 	}
+	return rechazados
 }
 
-func parsearDatosEmail(info *mail.Message) (emailJson string, err error) {
+func parsearDatosEmail(info *mail.Message, modTime time.Time) (emailJson []byte, err error) {
 	email := stEmail{}
-	email.Bcc = info.Header.Get("Bcc")
-	email.Cc = info.Header.Get("Cc")
+	//listas de direcciones {name, address, domain}
+	email.Bcc = mailparse.ParseAddressList(info.Header.Get("Bcc"))
+	email.Cc = mailparse.ParseAddressList(info.Header.Get("Cc"))
 	email.ContentType = info.Header.Get("Content-Type")
 
-	//email.Date = info.Header.Get("Date")
-	//cambiar campo texto a tipo time.Time
-	date, err := mail.ParseDate(info.Header.Get("Date"))
+	//fecha del header Date, de los headers Received o del archivo
+	date, source, err := mailparse.MessageDate(info.Header, modTime)
 
 	if err != nil {
-		log.Fatal(err)
+		return nil, ingest.NewFileError(ingest.StageDate, err)
 	}
 
 	//formatea fecha con formato por defecto de ZincSearch
 	email.Date = date.Format("2006-01-02T15:04:05Z07:00")
-	email.From = info.Header.Get("From")
-	email.MessageID = info.Header.Get("MessageID")
-	email.ReplyTo = info.Header.Get("Reply-To")
-	email.Sender = info.Header.Get("Sender")
-	email.Subject = info.Header.Get("Subject")
-	email.To = info.Header.Get("To")
-	txtBytes, _ := ioutil.ReadAll(info.Body)
-	email.TextBody = strings.TrimSuffix(string(txtBytes[:]), "\n")
-
-	jsonBytes, err := json.Marshal(email)
-	emailJson = string(jsonBytes)
-
-	return emailJson, err
+	email.DateSource = source
+	email.From = mailparse.ParseAddressList(info.Header.Get("From"))
+	email.ReplyTo = mailparse.DecodeHeader(info.Header.Get("Reply-To"))
+	email.Sender = mailparse.DecodeHeader(info.Header.Get("Sender"))
+	email.Subject = mailparse.DecodeHeader(info.Header.Get("Subject"))
+	email.To = mailparse.ParseAddressList(info.Header.Get("To"))
+
+	//ids de la conversacion: Message-ID, In-Reply-To, References y raiz del hilo
+	thread := mailparse.ParseThread(info.Header, email.Subject)
+	email.MessageID = thread.MessageID
+	email.InReplyTo = thread.InReplyTo
+	email.References = thread.References
+	email.ThreadID = thread.ThreadID
+
+	//partes MIME: texto plano, o HTML convertido a texto
+	body, err := mailparse.ParseMIME(textproto.MIMEHeader(info.Header), info.Body)
+	if err != nil {
+		return nil, ingest.NewFileError(ingest.StageParse, err)
+	}
+	email.TextBody = body.Text()
+
+	//adjuntos: nombre, tipo, tamaño, SHA-256 y texto
+	email.Attachments = body.Attachments()
+	if attachmentStore != nil {
+		for i := range email.Attachments {
+			if err = attachmentStore.Save(&email.Attachments[i]); err != nil {
+				return nil, ingest.NewFileError(ingest.StageAttachment, err)
+			}
+		}
+	}
+
+	//headers adicionales configurados
+	headers := headerCapture.Capture(info.Header)
+	if headersMode == "object" {
+		email.Headers = headers
+	}
+
+	emailJson, err = json.Marshal(email)
+	if err != nil {
+		return nil, ingest.NewFileError(ingest.StageMarshal, err)
+	}
+
+	if headersMode == "fields" && len(headers) > 0 {
+		emailJson, err = agregaCampos(emailJson, headers)
+		if err != nil {
+			return nil, ingest.NewFileError(ingest.StageMarshal, err)
+		}
+	}
+
+	return emailJson, nil
+}
+
+// agrega headers como campos del documento JSON, omitiendo los que ya
+// corresponden a un campo (Subject, From, Date, etc.)
+func agregaCampos(emailJson []byte, headers map[string]string) ([]byte, error) {
+	nombres := make([]string, 0, len(headers))
+	for nombre := range headers {
+		if !camposEmail[nombre] {
+			nombres = append(nombres, nombre)
+		}
+	}
+	if len(nombres) == 0 {
+		return emailJson, nil
+	}
+	sort.Strings(nombres)
+
+	//reemplaza la } final por los campos adicionales
+	buf := bytes.NewBuffer(emailJson[:len(emailJson)-1])
+	for _, nombre := range nombres {
+		clave, err := json.Marshal(nombre)
+		if err != nil {
+			return nil, err
+		}
+		valor, err := json.Marshal(headers[nombre])
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(',')
+		buf.Write(clave)
+		buf.WriteByte(':')
+		buf.Write(valor)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
 }
 
 // Estructura de email
 type stEmail struct {
 	Subject string
 	Sender  string
-	From    string
+	From    []mailparse.Address
 	ReplyTo string
-	To      string
-	Cc      string
-	Bcc     string
+	To      []mailparse.Address
+	Cc      []mailparse.Address
+	Bcc     []mailparse.Address
 	Date    string
+	//origen de la fecha: date, received o mtime
+	DateSource string
+
+	MessageID  string
+	InReplyTo  string
+	References []string
+	//raiz de la conversacion, ver mailparse.ParseThread
+	ThreadID string
 
-	MessageID   string
 	ContentType string
 
 	TextBody string
+
+	Attachments []mailparse.Attachment
+
+	//headers configurados en ZINC_LOCAL_HEADERS, con ZINC_LOCAL_HEADERS_MODE=object
+	Headers map[string]string `json:",omitempty"`
+}
+
+// headers con el mismo nombre que un campo de stEmail, no se agregan como campos
+var camposEmail = map[string]bool{
+	"Subject": true, "Sender": true, "From": true, "To": true, "Cc": true, "Bcc": true,
+	"Date": true, "References": true, "Headers": true, "Attachments": true,
 }
@@ -0,0 +1,178 @@
+package ingest
+
+import (
+	"log"
+	"time"
+
+	"zincsearch.com/mailindex/api/helpers"
+)
+
+// variables de ambiente para configuracion de lotes
+const BatchMaxDocs string = "ZINC_LOCAL_BATCH_MAX_DOCS"
+const BatchMaxBytes string = "ZINC_LOCAL_BATCH_MAX_BYTES"
+const BatchLinger string = "ZINC_LOCAL_BATCH_LINGER"
+
+const defaultBatchMaxDocs int = 5000
+const defaultBatchMaxBytes int = 32 << 20
+const defaultBatchLinger time.Duration = 10 * time.Second
+
+// bytes de la estructura {"index":"...","records":[...]} y separadores, aproximado
+const batchOverhead int = 64
+
+// bytes de la linea de accion NDJSON de un documento con id, sin incluir el id
+const docActionOverhead int = 48
+
+// Documento listo para enviarse a ZincSearch
+type Document struct {
+	//archivo de origen del documento
+	Source string
+	//id del documento, vacio utiliza id generado por el servidor
+	ID string
+	//documento serializado como JSON
+	Body []byte
+}
+
+// Lote de documentos enviado en una sola peticion bulk
+type Batch struct {
+	Docs []Document
+	//tamaño aproximado del cuerpo de la peticion
+	Bytes int
+}
+
+// Limites de un lote, se cierra al alcanzar el primero de ellos.
+//
+// Un documento que por si solo excede MaxBytes no se descarta: se envia en un
+// lote propio, cerrando antes el lote en curso, y se registra una advertencia.
+type BatchConfig struct {
+	//cantidad maxima de documentos, 0 sin limite
+	MaxDocs int
+	//tamaño maximo en bytes del cuerpo, 0 sin limite
+	MaxBytes int
+	//tiempo maximo desde el primer documento del lote, 0 sin limite
+	Linger time.Duration
+}
+
+// Limites por defecto: 5000 documentos, 32MB o 10 segundos
+func DefaultBatchConfig() BatchConfig {
+	return BatchConfig{
+		MaxDocs:  defaultBatchMaxDocs,
+		MaxBytes: defaultBatchMaxBytes,
+		Linger:   defaultBatchLinger,
+	}
+}
+
+// Obtiene limites de lote de variables de ambiente,
+// los valores no definidos conservan su valor por defecto
+func BatchConfigFromEnv() (cfg BatchConfig, err error) {
+	cfg = DefaultBatchConfig()
+
+	if cfg.MaxDocs, err = helpers.GetEnvInt(BatchMaxDocs, cfg.MaxDocs); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxBytes, err = helpers.GetEnvBytes(BatchMaxBytes, cfg.MaxBytes); err != nil {
+		return cfg, err
+	}
+	if cfg.Linger, err = helpers.GetEnvDuration(BatchLinger, cfg.Linger); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// Agrupa los documentos recibidos en lotes segun los limites configurados e invoca
+// flush con cada lote cerrado. Termina cuando se cierra el canal, enviando el
+// ultimo lote incompleto, o cuando flush retorna error
+func Batcher(in <-chan Document, cfg BatchConfig, flush func(Batch) error) error {
+	var batch Batch
+
+	//temporizador de antiguedad del lote, activo solo con documentos pendientes
+	var linger *time.Timer
+	var lingerC <-chan time.Time
+
+	send := func() error {
+		if linger != nil {
+			linger.Stop()
+			lingerC = nil
+		}
+		if len(batch.Docs) == 0 {
+			return nil
+		}
+		//cada lote utiliza su propio slice, puede enviarse en paralelo
+		closed := batch
+		batch = Batch{}
+		return flush(closed)
+	}
+
+	for {
+		select {
+		case doc, ok := <-in:
+			if !ok {
+				return send()
+			}
+
+			size := len(doc.Body) + 1
+			if doc.ID != "" {
+				size += len(doc.ID) + docActionOverhead
+			}
+
+			//documento mayor al limite: se envia solo
+			if cfg.MaxBytes > 0 && size+batchOverhead > cfg.MaxBytes {
+				log.Printf("Documento %s de %d bytes excede el limite de lote (%d bytes), se envia en un lote propio", doc.Source, size, cfg.MaxBytes)
+				if err := send(); err != nil {
+					return err
+				}
+				batch = Batch{Docs: []Document{doc}, Bytes: size + batchOverhead}
+				if err := send(); err != nil {
+					return err
+				}
+				continue
+			}
+
+			//el documento no cabe en el lote actual
+			if cfg.MaxBytes > 0 && batch.Bytes+size > cfg.MaxBytes {
+				if err := send(); err != nil {
+					return err
+				}
+			}
+
+			if len(batch.Docs) == 0 {
+				batch.Bytes = batchOverhead
+				if cfg.MaxDocs > 0 {
+					batch.Docs = make([]Document, 0, cfg.MaxDocs)
+				}
+				if cfg.Linger > 0 {
+					linger = resetTimer(linger, cfg.Linger)
+					lingerC = linger.C
+				}
+			}
+			batch.Docs = append(batch.Docs, doc)
+			batch.Bytes += size
+
+			if cfg.MaxDocs > 0 && len(batch.Docs) >= cfg.MaxDocs {
+				if err := send(); err != nil {
+					return err
+				}
+			}
+
+		case <-lingerC:
+			lingerC = nil
+			if err := send(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func resetTimer(timer *time.Timer, d time.Duration) *time.Timer {
+	if timer == nil {
+		return time.NewTimer(d)
+	}
+	if !timer.Stop() {
+		//descarta disparo pendiente
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+	return timer
+}
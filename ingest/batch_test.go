@@ -0,0 +1,160 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// documento sin id con cuerpo de n bytes, ocupa n+1 bytes en el lote
+func testDoc(name string, n int) Document {
+	return Document{Source: name, Body: []byte(strings.Repeat("x", n))}
+}
+
+// ejecuta Batcher con los documentos indicados y retorna los lotes enviados
+func runBatcher(t *testing.T, cfg BatchConfig, docs []Document) []Batch {
+	t.Helper()
+	in := make(chan Document, len(docs))
+	for _, doc := range docs {
+		in <- doc
+	}
+	close(in)
+
+	var batches []Batch
+	err := Batcher(in, cfg, func(batch Batch) error {
+		batches = append(batches, batch)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return batches
+}
+
+// fuentes de cada lote, para comparar
+func batchSources(batches []Batch) [][]string {
+	sources := make([][]string, len(batches))
+	for i, batch := range batches {
+		for _, doc := range batch.Docs {
+			sources[i] = append(sources[i], doc.Source)
+		}
+	}
+	return sources
+}
+
+func TestBatcherMaxDocs(t *testing.T) {
+	var docs []Document
+	for i := 0; i < 5; i++ {
+		docs = append(docs, testDoc(fmt.Sprint(i), 10))
+	}
+	batches := runBatcher(t, BatchConfig{MaxDocs: 2}, docs)
+
+	got := fmt.Sprint(batchSources(batches))
+	if want := "[[0 1] [2 3] [4]]"; got != want {
+		t.Errorf("lotes %s, se espera %s", got, want)
+	}
+}
+
+func TestBatcherMaxBytes(t *testing.T) {
+	//tres documentos de 101 bytes por lote
+	maxBytes := batchOverhead + 3*101
+	var docs []Document
+	for i := 0; i < 7; i++ {
+		docs = append(docs, testDoc(fmt.Sprint(i), 100))
+	}
+	batches := runBatcher(t, BatchConfig{MaxBytes: maxBytes}, docs)
+
+	got := fmt.Sprint(batchSources(batches))
+	if want := "[[0 1 2] [3 4 5] [6]]"; got != want {
+		t.Errorf("lotes %s, se espera %s", got, want)
+	}
+	for i, batch := range batches {
+		if want := batchOverhead + 101*len(batch.Docs); batch.Bytes != want || batch.Bytes > maxBytes {
+			t.Errorf("lote %d: Bytes = %d, se espera %d (limite %d)", i, batch.Bytes, want, maxBytes)
+		}
+	}
+}
+
+func TestBatcherIDCountsTowardsBytes(t *testing.T) {
+	doc := testDoc("a", 100)
+	doc.ID = "0123456789"
+	batches := runBatcher(t, BatchConfig{}, []Document{doc})
+
+	if want := batchOverhead + 101 + len(doc.ID) + docActionOverhead; len(batches) != 1 || batches[0].Bytes != want {
+		t.Errorf("lotes %+v, se espera uno de %d bytes", batchSources(batches), want)
+	}
+}
+
+func TestBatcherOversizedDocument(t *testing.T) {
+	maxBytes := batchOverhead + 3*101
+	docs := []Document{
+		testDoc("a", 100),
+		testDoc("grande", 1000),
+		testDoc("b", 100),
+		testDoc("c", 100),
+	}
+	batches := runBatcher(t, BatchConfig{MaxBytes: maxBytes}, docs)
+
+	//el lote en curso se cierra antes del documento grande, que va solo
+	got := fmt.Sprint(batchSources(batches))
+	if want := "[[a] [grande] [b c]]"; got != want {
+		t.Errorf("lotes %s, se espera %s", got, want)
+	}
+}
+
+func TestBatcherLinger(t *testing.T) {
+	in := make(chan Document)
+	flushed := make(chan Batch, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- Batcher(in, BatchConfig{MaxDocs: 100, Linger: 20 * time.Millisecond}, func(batch Batch) error {
+			flushed <- batch
+			return nil
+		})
+	}()
+
+	in <- testDoc("a", 10)
+	in <- testDoc("b", 10)
+
+	//el lote incompleto se envia al cumplirse Linger, sin cerrar el canal
+	select {
+	case batch := <-flushed:
+		if got := fmt.Sprint(batchSources([]Batch{batch})); got != "[[a b]]" {
+			t.Errorf("lote %s, se espera [[a b]]", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Linger no envio el lote incompleto")
+	}
+
+	//el siguiente documento inicia un lote nuevo
+	in <- testDoc("c", 10)
+	close(in)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if batch := <-flushed; len(batch.Docs) != 1 || batch.Docs[0].Source != "c" {
+		t.Errorf("ultimo lote %v, se espera [c]", batchSources([]Batch{batch}))
+	}
+	if len(flushed) != 0 {
+		t.Errorf("%d lotes adicionales", len(flushed))
+	}
+}
+
+func TestBatcherFlushError(t *testing.T) {
+	errFlush := errors.New("envio fallido")
+	in := make(chan Document, 3)
+	for i := 0; i < 3; i++ {
+		in <- testDoc(fmt.Sprint(i), 10)
+	}
+
+	calls := 0
+	err := Batcher(in, BatchConfig{MaxDocs: 1}, func(batch Batch) error {
+		calls++
+		return errFlush
+	})
+	if !errors.Is(err, errFlush) || calls != 1 {
+		t.Errorf("Batcher retorna %v despues de %d envios, se espera %v despues de 1", err, calls, errFlush)
+	}
+}
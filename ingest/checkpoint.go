@@ -0,0 +1,186 @@
+package ingest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// variable de ambiente con la ruta del archivo de checkpoint
+const CheckpointFile string = "ZINC_LOCAL_CHECKPOINT_FILE"
+
+const defaultCheckpointFile string = "indexer.checkpoint"
+
+// Obtiene ruta del archivo de checkpoint de variables de ambiente
+func CheckpointFileFromEnv() string {
+	if path := os.Getenv(CheckpointFile); path != "" {
+		return path
+	}
+	return defaultCheckpointFile
+}
+
+// Registro de archivos cuyos documentos fueron confirmados por ZincSearch.
+//
+// Cada lote confirmado se agrega como una linea JSON con la lista de archivos,
+// escrita en una sola operacion y sincronizada a disco antes de continuar. Si el
+// proceso termina a mitad de una escritura, la ultima linea queda incompleta y se
+// descarta al cargar: ese lote se considera no confirmado y se vuelve a enviar.
+type Checkpoint struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[string]struct{}
+}
+
+// Abre archivo de checkpoint. Con resume se cargan los archivos ya confirmados,
+// de lo contrario se inicia un checkpoint vacio. En ambos casos el archivo se
+// reescribe de forma atomica (archivo temporal + rename) antes de agregar lotes
+func OpenCheckpoint(path string, resume bool) (*Checkpoint, error) {
+	c := &Checkpoint{done: map[string]struct{}{}}
+
+	if resume {
+		if err := c.load(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	//compacta en un solo registro los archivos confirmados
+	if err := c.rewrite(path); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	c.file = file
+	return c, nil
+}
+
+// Indica si el archivo ya fue confirmado por el servidor
+func (c *Checkpoint) Done(path string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.done[path]
+	return ok
+}
+
+// Cantidad de archivos confirmados
+func (c *Checkpoint) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.done)
+}
+
+// Registra archivos confirmados por el servidor, debe invocarse solo despues de
+// una respuesta exitosa
+func (c *Checkpoint) Ack(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	line, err := json.Marshal(paths)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err = c.file.Write(line); err != nil {
+		return err
+	}
+	if err = c.file.Sync(); err != nil {
+		return err
+	}
+	for _, path := range paths {
+		c.done[path] = struct{}{}
+	}
+	return nil
+}
+
+func (c *Checkpoint) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.file.Close()
+}
+
+// carga lineas completas del checkpoint, ignorando una ultima linea incompleta
+func (c *Checkpoint) load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for lineNum := 1; ; lineNum++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				log.Printf("Checkpoint %s: se descarta linea %d incompleta", path, lineNum)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var paths []string
+		if err := json.Unmarshal(line, &paths); err != nil {
+			log.Printf("Checkpoint %s: se descarta linea %d invalida: %s", path, lineNum, err)
+			continue
+		}
+		for _, p := range paths {
+			c.done[p] = struct{}{}
+		}
+	}
+}
+
+// reemplaza el archivo de checkpoint con los archivos confirmados en memoria
+func (c *Checkpoint) rewrite(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if len(c.done) > 0 {
+		paths := make([]string, 0, len(c.done))
+		for p := range c.done {
+			paths = append(paths, p)
+		}
+		line, err := json.Marshal(paths)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		if _, err = tmp.Write(append(line, '\n')); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	//sincroniza el directorio para persistir el rename
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package ingest
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// variable de ambiente con la ruta del archivo de dead letters
+const DeadLetterFile string = "ZINC_LOCAL_DEAD_LETTER_FILE"
+
+const defaultDeadLetterFile string = "indexer.deadletter.jsonl"
+
+// Obtiene ruta del archivo de dead letters de variables de ambiente
+func DeadLetterFileFromEnv() string {
+	if path := os.Getenv(DeadLetterFile); path != "" {
+		return path
+	}
+	return defaultDeadLetterFile
+}
+
+// Archivo que no pudo cargarse, con la etapa y el error que lo impidio
+type DeadLetter struct {
+	Path  string    `json:"path"`
+	Stage string    `json:"stage"`
+	Error string    `json:"error"`
+	Time  time.Time `json:"time"`
+}
+
+// Registro de archivos fallidos, una linea JSON por archivo.
+// Cada registro se sincroniza a disco antes de continuar; si el proceso termina a
+// mitad de una escritura, la ultima linea incompleta se descarta al leer
+type DeadLetters struct {
+	mu    sync.Mutex
+	file  *os.File
+	count int
+}
+
+// Abre archivo de dead letters. Con resume se agregan registros al final,
+// de lo contrario se reinicia el archivo
+func OpenDeadLetters(path string, resume bool) (*DeadLetters, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if !resume {
+		flags |= os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &DeadLetters{file: file}, nil
+}
+
+// Registra archivo fallido en la etapa indicada
+func (d *DeadLetters) Record(path string, stage string, err error) error {
+	line, errJson := json.Marshal(DeadLetter{Path: path, Stage: stage, Error: err.Error(), Time: time.Now()})
+	if errJson != nil {
+		return errJson
+	}
+	line = append(line, '\n')
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, err := d.file.Write(line); err != nil {
+		return err
+	}
+	if err := d.file.Sync(); err != nil {
+		return err
+	}
+	d.count++
+	return nil
+}
+
+// Cantidad de archivos registrados desde que se abrio el archivo
+func (d *DeadLetters) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.count
+}
+
+func (d *DeadLetters) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.file.Close()
+}
+
+// Lee registros completos del archivo de dead letters, ignorando lineas invalidas
+// o una ultima linea incompleta
+func ReadDeadLetters(path string) (letters []DeadLetter, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for lineNum := 1; ; lineNum++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				log.Printf("Dead letters %s: se descarta linea %d incompleta", path, lineNum)
+			}
+			return letters, nil
+		}
+		if err != nil {
+			return letters, err
+		}
+
+		var letter DeadLetter
+		if err := json.Unmarshal(line, &letter); err != nil || letter.Path == "" {
+			log.Printf("Dead letters %s: se descarta linea %d invalida", path, lineNum)
+			continue
+		}
+		letters = append(letters, letter)
+	}
+}
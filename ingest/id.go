@@ -0,0 +1,57 @@
+package ingest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// variable de ambiente con el modo de generacion de id de documento
+const DocIDMode string = "ZINC_LOCAL_DOC_ID"
+
+// Modos de generacion de id de documento
+const (
+	//id generado por el servidor, cada carga crea documentos nuevos
+	DocIDServer string = ""
+	//id derivado del header Message-ID, o del contenido si no existe. Las copias
+	//de un correo en distintas carpetas comparten id y queda la ultima en
+	//escribirse, que depende del orden de procesamiento y envio: los campos
+	//propios de cada archivo (X-Folder, X-FileName) pueden variar entre cargas
+	DocIDMessageID string = "message-id"
+	//id derivado del contenido del archivo
+	DocIDContentHash string = "hash"
+)
+
+// Obtiene modo de generacion de id de variables de ambiente
+func DocIDModeFromEnv() (string, error) {
+	mode := strings.ToLower(os.Getenv(DocIDMode))
+	switch mode {
+	case DocIDServer, DocIDMessageID, DocIDContentHash:
+		return mode, nil
+	}
+	return mode, fmt.Errorf("valor invalido para %s: %q, se espera %q o %q", DocIDMode, mode, DocIDMessageID, DocIDContentHash)
+}
+
+// Genera id deterministico de documento: el mismo correo produce siempre el mismo
+// id, por lo que una nueva carga reemplaza los documentos en lugar de duplicarlos.
+//
+// Con DocIDMessageID se utiliza messageID, ya normalizado con
+// mailparse.NormalizeMessageID como el campo MessageID del documento; si esta
+// vacio, o con DocIDContentHash, se utiliza el contenido completo del archivo.
+// El id es el SHA-256 en hexadecimal, seguro para utilizarse en URLs
+func DocumentID(mode string, messageID string, content []byte) string {
+	switch mode {
+	case DocIDMessageID:
+		if messageID != "" {
+			sum := sha256.Sum256([]byte("message-id:" + messageID))
+			return hex.EncodeToString(sum[:])
+		}
+		fallthrough
+	case DocIDContentHash:
+		sum := sha256.Sum256(content)
+		return hex.EncodeToString(sum[:])
+	}
+	return ""
+}
@@ -0,0 +1,26 @@
+package ingest
+
+import "testing"
+
+func TestDocumentID(t *testing.T) {
+	content := []byte("Message-ID: <a@x>\r\n\r\nbody")
+
+	byMessageID := DocumentID(DocIDMessageID, "a@x", content)
+	if byMessageID == "" || byMessageID != DocumentID(DocIDMessageID, "a@x", []byte("otro contenido")) {
+		t.Errorf("el id con %q no depende solo del Message-ID: %q", DocIDMessageID, byMessageID)
+	}
+
+	byContent := DocumentID(DocIDContentHash, "a@x", content)
+	if byContent == byMessageID || byContent != DocumentID(DocIDContentHash, "b@x", content) {
+		t.Errorf("el id con %q no depende solo del contenido: %q", DocIDContentHash, byContent)
+	}
+
+	//sin Message-ID se utiliza el contenido
+	if got := DocumentID(DocIDMessageID, "", content); got != byContent {
+		t.Errorf("DocumentID sin Message-ID = %q, se espera %q", got, byContent)
+	}
+
+	if got := DocumentID(DocIDServer, "a@x", content); got != "" {
+		t.Errorf("DocumentID con id del servidor = %q, se espera vacio", got)
+	}
+}
@@ -0,0 +1,214 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+)
+
+// Etapas del procesamiento de un archivo, utilizadas como motivo de omision
+// y registradas en el archivo de dead letters
+const (
+	StageRead    string = "read"
+	StageParse   string = "parse"
+	StageDate    string = "date"
+	StageMarshal string = "marshal"
+	//error al guardar adjuntos en el almacen
+	StageAttachment string = "attachment"
+	//documento rechazado por el servidor
+	StageServerReject string = "server-reject"
+)
+
+// motivo de omision de archivos confirmados en una ejecucion anterior
+const SkipCheckpoint string = "checkpoint"
+
+// Error al procesar un archivo, indica la etapa en que ocurrio
+type FileError struct {
+	Stage string
+	Err   error
+}
+
+func NewFileError(stage string, err error) *FileError {
+	return &FileError{Stage: stage, Err: err}
+}
+
+func (e *FileError) Error() string {
+	return e.Stage + ": " + e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// Documento de un lote rechazado por el servidor
+type Rejection struct {
+	//archivo de origen del documento
+	Source string
+	Err    error
+}
+
+// obtiene etapa del error, "error" si no se indico, y el error sin la etapa
+func stageOf(err error) (string, error) {
+	var fileErr *FileError
+	if errors.As(err, &fileErr) {
+		return fileErr.Stage, fileErr.Err
+	}
+	return "error", err
+}
+
+// Configuracion de una carga completa
+type RunConfig struct {
+	//directorio con los archivos de correo
+	Root string
+	//archivos a procesar en lugar de recorrer Root, por ejemplo al reintentar dead letters
+	Files         []string
+	ParserWorkers int
+	Batch         BatchConfig
+	Sender        SenderConfig
+
+	//archivos confirmados por el servidor; los registrados se omiten y cada
+	//lote enviado con exito se agrega. nil deshabilita el checkpoint
+	Checkpoint *Checkpoint
+
+	//archivos que no pudieron procesarse y documentos rechazados por el servidor,
+	//la carga continua con los demas archivos. nil solo los contabiliza
+	DeadLetters *DeadLetters
+
+	//convierte un archivo en documento; un error omite el archivo, registrando
+	//como motivo la etapa de un *FileError
+	Parse func(path string) (Document, error)
+	//envia un lote a ZincSearch. Los documentos rechazados individualmente se
+	//retornan sin error; un error detiene la carga
+	Send func(ctx context.Context, batch Batch) ([]Rejection, error)
+	//total de reintentos del cliente HTTP desde que inicio el proceso, por ejemplo
+	//ZincSearch.RetryStats().Retries. Los realizados durante la carga se registran
+	//en IngestStats.Retries; nil lo deja en 0
+	Retries func() int64
+}
+
+// Ejecuta la carga: recorre Root, procesa los archivos con ParserWorkers workers,
+// agrupa los documentos en lotes y los envia con Sender.Workers workers.
+// Retorna los contadores de la carga, tambien cuando termina con error
+func Run(ctx context.Context, cfg RunConfig) (*IngestStats, error) {
+	stats := &IngestStats{}
+
+	if cfg.Retries != nil {
+		start := cfg.Retries()
+		defer func() { stats.Retries.Store(cfg.Retries() - start) }()
+	}
+
+	//rutas absolutas, el checkpoint no depende del directorio de trabajo
+	root, err := filepath.Abs(cfg.Root)
+	if err != nil {
+		return stats, err
+	}
+
+	//se cancela si falla el envio, para detener el procesamiento de archivos
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	docs := make(chan Document)
+	walkErr := make(chan error, 1)
+
+	//registra archivo fallido, un error al escribir el registro detiene la carga
+	deadLetterErr := make(chan error, 1)
+	deadLetter := func(path string, stage string, err error) {
+		if cfg.DeadLetters == nil {
+			return
+		}
+		if errRecord := cfg.DeadLetters.Record(path, stage, err); errRecord != nil {
+			select {
+			case deadLetterErr <- errRecord:
+			default:
+			}
+			cancel()
+		}
+	}
+
+	parse := func(path string) {
+		//confirmado en una ejecucion anterior
+		if cfg.Checkpoint != nil && cfg.Checkpoint.Done(path) {
+			stats.Skip(SkipCheckpoint)
+			return
+		}
+
+		doc, err := cfg.Parse(path)
+		if err != nil {
+			stage, cause := stageOf(err)
+			stats.Skip(stage)
+			deadLetter(path, stage, cause)
+			return
+		}
+		stats.FilesParsed.Add(1)
+
+		select {
+		case docs <- doc:
+		case <-runCtx.Done():
+		}
+	}
+
+	//directorio o archivo que no pudo leerse durante el recorrido, la carga continua
+	walkFail := func(path string, err error) {
+		stats.Skip(StageRead)
+		deadLetter(path, StageRead, err)
+	}
+
+	go func() {
+		//cierra la cola al terminar, para enviar el ultimo lote
+		defer close(docs)
+
+		if cfg.Files != nil {
+			walkErr <- WalkFiles(runCtx, cfg.Files, cfg.ParserWorkers, stats, parse, walkFail)
+			return
+		}
+		walkErr <- Walk(runCtx, root, cfg.ParserWorkers, stats, parse, walkFail)
+	}()
+
+	sender := NewSender(runCtx, cfg.Sender, func(ctx context.Context, batch Batch) error {
+		rejected, err := cfg.Send(ctx, batch)
+		if err != nil {
+			return err
+		}
+		stats.batchSent(batch)
+
+		//los documentos rechazados se registran como dead letters y se confirman en
+		//el checkpoint junto con el lote: se vuelven a enviar con el reintento de dead letters
+		stats.DocumentsRejected.Add(int64(len(rejected)))
+		for _, r := range rejected {
+			deadLetter(r.Source, StageServerReject, r.Err)
+		}
+
+		//registra archivos solo despues de la confirmacion del servidor
+		if cfg.Checkpoint != nil {
+			sources := make([]string, len(batch.Docs))
+			for i, doc := range batch.Docs {
+				sources[i] = doc.Source
+			}
+			return cfg.Checkpoint.Ack(sources)
+		}
+		return nil
+	})
+
+	err = Batcher(docs, cfg.Batch, sender.Send)
+
+	//espera envio de lotes pendientes
+	if errClose := sender.Close(); err == nil {
+		err = errClose
+	}
+
+	//detiene el recorrido si el envio fallo
+	cancel()
+	if errWalk := <-walkErr; err == nil && ctx.Err() == nil && !errors.Is(errWalk, context.Canceled) {
+		err = errWalk
+	}
+	select {
+	case errRecord := <-deadLetterErr:
+		err = errRecord
+	default:
+	}
+
+	if err == nil {
+		err = ctx.Err()
+	}
+	return stats, err
+}
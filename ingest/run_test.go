@@ -0,0 +1,276 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// crea arbol de directorios con files archivos repartidos en dirs subdirectorios;
+// los archivos cuyo nombre termina en .bad no pueden procesarse
+func writeTree(t *testing.T, dirs int, files int, bad int) string {
+	t.Helper()
+	root := t.TempDir()
+	for i := 0; i < files+bad; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("d%d", i%dirs), fmt.Sprintf("s%d", i%3))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		name := fmt.Sprintf("%d.", i)
+		if i >= files {
+			name += "bad"
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(fmt.Sprintf(`{"n":%d}`, i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func parseStub(path string) (Document, error) {
+	if strings.HasSuffix(path, ".bad") {
+		return Document{}, NewFileError(StageParse, errors.New("formato invalido"))
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return Document{}, NewFileError(StageRead, err)
+	}
+	return Document{Source: path, Body: body}, nil
+}
+
+// registra documentos recibidos por send, seguro para varios workers
+type sendRecorder struct {
+	mu      sync.Mutex
+	sources map[string]int
+	batches int
+}
+
+func (r *sendRecorder) send(ctx context.Context, batch Batch) ([]Rejection, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sources == nil {
+		r.sources = map[string]int{}
+	}
+	r.batches++
+	for _, doc := range batch.Docs {
+		r.sources[doc.Source]++
+	}
+	return nil, nil
+}
+
+func testConfig(root string, send func(context.Context, Batch) ([]Rejection, error)) RunConfig {
+	return RunConfig{
+		Root:          root,
+		ParserWorkers: 4,
+		Batch:         BatchConfig{MaxDocs: 7, Linger: time.Second},
+		Sender:        SenderConfig{Workers: 3, MaxInFlight: 4},
+		Parse:         parseStub,
+		Send:          send,
+	}
+}
+
+func TestRunCountsEveryFile(t *testing.T) {
+	const files, bad = 200, 5
+	root := writeTree(t, 4, files, bad)
+
+	var retries atomic.Int64
+	retries.Store(10)
+
+	recorder := &sendRecorder{}
+	cfg := testConfig(root, func(ctx context.Context, batch Batch) ([]Rejection, error) {
+		retries.Add(1)
+		return recorder.send(ctx, batch)
+	})
+	cfg.Retries = retries.Load
+
+	stats, err := Run(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := stats.Snapshot()
+	if snapshot.FilesSeen != files+bad {
+		t.Errorf("FilesSeen = %d, se espera %d", snapshot.FilesSeen, files+bad)
+	}
+	if snapshot.FilesParsed != files || snapshot.DocumentsSent != files {
+		t.Errorf("FilesParsed = %d, DocumentsSent = %d, se espera %d", snapshot.FilesParsed, snapshot.DocumentsSent, files)
+	}
+	if snapshot.FilesSkipped != bad || snapshot.SkipReasons[StageParse] != bad {
+		t.Errorf("FilesSkipped = %d, motivos %v, se espera %d por %s", snapshot.FilesSkipped, snapshot.SkipReasons, bad, StageParse)
+	}
+	//4 directorios con 3 subdirectorios cada uno
+	if snapshot.Directories != 16 {
+		t.Errorf("Directories = %d, se espera 16", snapshot.Directories)
+	}
+	if snapshot.Batches != int64(recorder.batches) {
+		t.Errorf("Batches = %d, se enviaron %d", snapshot.Batches, recorder.batches)
+	}
+	//solo los reintentos durante la carga, uno por lote en el stub
+	if snapshot.Retries != int64(recorder.batches) {
+		t.Errorf("Retries = %d, se espera %d", snapshot.Retries, recorder.batches)
+	}
+
+	if len(recorder.sources) != files {
+		t.Errorf("se enviaron %d archivos distintos, se espera %d", len(recorder.sources), files)
+	}
+	for source, n := range recorder.sources {
+		if n != 1 {
+			t.Errorf("%s enviado %d veces", source, n)
+		}
+	}
+}
+
+func TestRunCheckpointAndDeadLetters(t *testing.T) {
+	const files, bad = 50, 3
+	root := writeTree(t, 2, files, bad)
+	dir := t.TempDir()
+	checkpointPath := filepath.Join(dir, "checkpoint")
+	deadLetterPath := filepath.Join(dir, "deadletter.jsonl")
+
+	run := func(resume bool, send func(context.Context, Batch) ([]Rejection, error)) *IngestStats {
+		t.Helper()
+		checkpoint, err := OpenCheckpoint(checkpointPath, resume)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer checkpoint.Close()
+		deadLetters, err := OpenDeadLetters(deadLetterPath, resume)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer deadLetters.Close()
+
+		cfg := testConfig(root, send)
+		cfg.Checkpoint = checkpoint
+		cfg.DeadLetters = deadLetters
+		stats, err := Run(context.Background(), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stats
+	}
+
+	//el servidor rechaza un documento
+	var rejectedSource atomic.Value
+	first := &sendRecorder{}
+	run(false, func(ctx context.Context, batch Batch) ([]Rejection, error) {
+		first.send(ctx, batch)
+		if rejectedSource.CompareAndSwap(nil, batch.Docs[0].Source) {
+			return []Rejection{{Source: batch.Docs[0].Source, Err: errors.New("status 400")}}, nil
+		}
+		return nil, nil
+	})
+
+	letters, err := ReadDeadLetters(deadLetterPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stages := map[string]int{}
+	for _, letter := range letters {
+		stages[letter.Stage]++
+	}
+	if stages[StageParse] != bad || stages[StageServerReject] != 1 || len(letters) != bad+1 {
+		t.Errorf("dead letters por etapa %v, se espera %d %s y 1 %s", stages, bad, StageParse, StageServerReject)
+	}
+
+	//al continuar, todos los archivos enviados ya estan confirmados
+	second := &sendRecorder{}
+	stats := run(true, second.send)
+	if len(second.sources) != 0 {
+		t.Errorf("se reenviaron %d archivos confirmados", len(second.sources))
+	}
+	if n := stats.Snapshot().SkipReasons[SkipCheckpoint]; n != files {
+		t.Errorf("omitidos por checkpoint = %d, se espera %d", n, files)
+	}
+}
+
+func TestRunStopsOnSendError(t *testing.T) {
+	root := writeTree(t, 2, 100, 0)
+	errSend := errors.New("servidor no disponible")
+
+	cfg := testConfig(root, func(ctx context.Context, batch Batch) ([]Rejection, error) {
+		return nil, errSend
+	})
+	_, err := Run(context.Background(), cfg)
+	if !errors.Is(err, errSend) {
+		t.Fatalf("Run retorna %v, se espera %v", err, errSend)
+	}
+}
+
+func TestRunContinuesAfterUnreadableDirectory(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root puede leer directorios sin permisos")
+	}
+	const files = 30
+	root := writeTree(t, 3, files, 0)
+	locked := filepath.Join(root, "d1")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0755) })
+
+	deadLetterPath := filepath.Join(t.TempDir(), "deadletter.jsonl")
+	deadLetters, err := OpenDeadLetters(deadLetterPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deadLetters.Close()
+
+	recorder := &sendRecorder{}
+	cfg := testConfig(root, recorder.send)
+	cfg.DeadLetters = deadLetters
+	stats, err := Run(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Run se detuvo por un directorio sin permisos: %v", err)
+	}
+
+	//los archivos de d0 y d2 se envian
+	if want := files - files/3; len(recorder.sources) != want {
+		t.Errorf("se enviaron %d archivos, se espera %d", len(recorder.sources), want)
+	}
+	if n := stats.Snapshot().SkipReasons[StageRead]; n != 1 {
+		t.Errorf("omitidos por %s = %d, se espera 1", StageRead, n)
+	}
+
+	letters, err := ReadDeadLetters(deadLetterPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 || letters[0].Path != locked || letters[0].Stage != StageRead {
+		t.Errorf("dead letters %+v, se espera %s con etapa %s", letters, locked, StageRead)
+	}
+}
+
+func TestRunFilesWalksDirectories(t *testing.T) {
+	root := writeTree(t, 2, 20, 0)
+	single := filepath.Join(root, "d0", "s0", "0.")
+
+	//un directorio registrado como dead letter se recorre completo al reintentar
+	recorder := &sendRecorder{}
+	cfg := testConfig(root, recorder.send)
+	cfg.Files = []string{filepath.Join(root, "d1"), single}
+	stats, err := Run(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.sources) != 11 || recorder.sources[single] != 1 {
+		t.Errorf("se enviaron %d archivos, se espera 11 incluyendo %s", len(recorder.sources), single)
+	}
+	if n := stats.Snapshot().FilesSeen; n != 11 {
+		t.Errorf("FilesSeen = %d, se espera 11", n)
+	}
+}
+
+func TestRunMissingRoot(t *testing.T) {
+	cfg := testConfig(filepath.Join(t.TempDir(), "no-existe"), (&sendRecorder{}).send)
+	if _, err := Run(context.Background(), cfg); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Run retorna %v, se espera %v", err, os.ErrNotExist)
+	}
+}
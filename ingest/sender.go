@@ -0,0 +1,163 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"zincsearch.com/mailindex/api/helpers"
+)
+
+// variables de ambiente para configuracion del envio de lotes
+const SenderWorkers string = "ZINC_LOCAL_SENDER_WORKERS"
+const SenderMaxInFlight string = "ZINC_LOCAL_SENDER_MAX_IN_FLIGHT"
+
+const defaultSenderWorkers int = 4
+
+// ErrSenderClosed se retorna al enviar un lote despues de Close
+var ErrSenderClosed = errors.New("ingest: sender cerrado")
+
+// Configuracion del envio concurrente de lotes
+type SenderConfig struct {
+	//cantidad de peticiones bulk simultaneas
+	Workers int
+	//lotes entregados al sender que aun no terminan de enviarse, incluyendo los
+	//que estan en espera. Al alcanzarlo Send bloquea, deteniendo la generacion de lotes
+	MaxInFlight int
+}
+
+// Configuracion por defecto: 4 workers y hasta 8 lotes en vuelo
+func DefaultSenderConfig() SenderConfig {
+	return SenderConfig{
+		Workers:     defaultSenderWorkers,
+		MaxInFlight: 2 * defaultSenderWorkers,
+	}
+}
+
+// Obtiene configuracion de envio de variables de ambiente,
+// los valores no definidos conservan su valor por defecto
+func SenderConfigFromEnv() (cfg SenderConfig, err error) {
+	cfg = DefaultSenderConfig()
+
+	if cfg.Workers, err = helpers.GetEnvInt(SenderWorkers, cfg.Workers); err != nil {
+		return cfg, err
+	}
+	//por defecto el doble de workers, para que siempre haya un lote listo
+	if cfg.MaxInFlight, err = helpers.GetEnvInt(SenderMaxInFlight, 2*cfg.Workers); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// Envia lotes a ZincSearch con varios workers en paralelo.
+//
+// Los lotes se toman de la cola en el orden en que se entregan, pero pueden
+// terminar en cualquier orden: no hay garantia de orden entre lotes, solo dentro
+// de cada lote. Con Workers = 1 los lotes se envian estrictamente en orden.
+//
+// Si un envio falla, se cancela el contexto de los demas y Send y Close retornan
+// el primer error.
+type Sender struct {
+	queue chan Batch
+	//un espacio por lote en vuelo
+	slots chan struct{}
+	send  func(context.Context, Batch) error
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	err    error
+	closed bool
+}
+
+// Crea sender e inicia sus workers; send se invoca con cada lote
+func NewSender(ctx context.Context, cfg SenderConfig, send func(context.Context, Batch) error) *Sender {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxInFlight < 1 {
+		cfg.MaxInFlight = cfg.Workers
+	}
+
+	s := &Sender{
+		queue: make(chan Batch, cfg.MaxInFlight),
+		slots: make(chan struct{}, cfg.MaxInFlight),
+		send:  send,
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+
+	for i := 0; i < cfg.Workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	return s
+}
+
+// Entrega lote para su envio, bloquea mientras se alcance el limite de lotes en vuelo
+func (s *Sender) Send(batch Batch) error {
+	select {
+	case s.slots <- struct{}{}:
+	case <-s.ctx.Done():
+		return s.failure()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		<-s.slots
+		return ErrSenderClosed
+	}
+	s.queue <- batch
+	return nil
+}
+
+// Espera el envio de los lotes pendientes y retorna el primer error
+func (s *Sender) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	s.cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *Sender) worker() {
+	defer s.wg.Done()
+
+	for batch := range s.queue {
+		//despues de un error se descartan los lotes pendientes
+		if s.ctx.Err() == nil {
+			if err := s.send(s.ctx, batch); err != nil {
+				s.fail(err)
+			}
+		}
+		<-s.slots
+	}
+}
+
+func (s *Sender) fail(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.cancel()
+}
+
+func (s *Sender) failure() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	return s.ctx.Err()
+}
@@ -0,0 +1,131 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSenderBlocksAtMaxInFlight(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	sender := NewSender(context.Background(), SenderConfig{Workers: 1, MaxInFlight: 2}, func(ctx context.Context, batch Batch) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+
+	//dos lotes en vuelo: uno enviandose y otro en espera
+	for i := 0; i < 2; i++ {
+		if err := sender.Send(Batch{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-started
+
+	third := make(chan error, 1)
+	go func() { third <- sender.Send(Batch{}) }()
+
+	select {
+	case err := <-third:
+		t.Fatalf("Send no bloqueo con %d lotes en vuelo (error %v)", 2, err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	//al terminar un envio se libera un espacio
+	release <- struct{}{}
+	select {
+	case err := <-third:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send sigue bloqueado despues de liberar un lote")
+	}
+
+	close(release)
+	if err := sender.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(started); n != 2 {
+		t.Errorf("se enviaron %d lotes adicionales, se esperan 2", n)
+	}
+}
+
+func TestSenderSendsConcurrently(t *testing.T) {
+	const workers = 3
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	ready := make(chan struct{})
+	sender := NewSender(context.Background(), SenderConfig{Workers: workers, MaxInFlight: workers}, func(ctx context.Context, batch Batch) error {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		if active == workers {
+			close(ready)
+		}
+		mu.Unlock()
+
+		//espera a que todos los workers esten enviando
+		select {
+		case <-ready:
+		case <-time.After(5 * time.Second):
+		}
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return nil
+	})
+
+	for i := 0; i < workers; i++ {
+		if err := sender.Send(Batch{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sender.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if maxActive != workers {
+		t.Errorf("maximo de envios simultaneos %d, se espera %d", maxActive, workers)
+	}
+}
+
+func TestSenderFailure(t *testing.T) {
+	errSend := errors.New("servidor no disponible")
+	sender := NewSender(context.Background(), SenderConfig{Workers: 1, MaxInFlight: 1}, func(ctx context.Context, batch Batch) error {
+		return errSend
+	})
+
+	if err := sender.Send(Batch{}); err != nil {
+		t.Fatal(err)
+	}
+
+	//despues del error Send deja de aceptar lotes
+	deadline := time.After(5 * time.Second)
+	for {
+		err := sender.Send(Batch{})
+		if errors.Is(err, errSend) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Send retorna %v, se espera %v", err, errSend)
+		}
+		select {
+		case <-deadline:
+			t.Fatal("Send no retorna el error del envio")
+		default:
+		}
+	}
+
+	if err := sender.Close(); !errors.Is(err, errSend) {
+		t.Errorf("Close retorna %v, se espera %v", err, errSend)
+	}
+	if err := sender.Send(Batch{}); err == nil {
+		t.Error("Send despues de Close no retorna error")
+	}
+}
@@ -0,0 +1,118 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Contadores de una carga, seguros para actualizarse desde varias goroutines
+type IngestStats struct {
+	Directories   atomic.Int64
+	FilesSeen     atomic.Int64
+	FilesParsed   atomic.Int64
+	FilesSkipped  atomic.Int64
+	DocumentsSent atomic.Int64
+	//documentos enviados que el servidor rechazo
+	DocumentsRejected atomic.Int64
+	Batches           atomic.Int64
+	Bytes             atomic.Int64
+	//reintentos de peticiones durante la carga, ver RunConfig.Retries
+	Retries atomic.Int64
+
+	mu          sync.Mutex
+	skipReasons map[string]int64
+}
+
+// Registra archivo omitido con el motivo indicado
+func (s *IngestStats) Skip(reason string) {
+	s.FilesSkipped.Add(1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.skipReasons == nil {
+		s.skipReasons = map[string]int64{}
+	}
+	s.skipReasons[reason]++
+}
+
+// registra lote enviado
+func (s *IngestStats) batchSent(batch Batch) {
+	s.Batches.Add(1)
+	s.DocumentsSent.Add(int64(len(batch.Docs)))
+	s.Bytes.Add(int64(batch.Bytes))
+}
+
+// Copia de los contadores en un momento dado
+type StatsSnapshot struct {
+	Directories       int64            `json:"directories"`
+	FilesSeen         int64            `json:"files_seen"`
+	FilesParsed       int64            `json:"files_parsed"`
+	FilesSkipped      int64            `json:"files_skipped"`
+	SkipReasons       map[string]int64 `json:"skip_reasons,omitempty"`
+	DocumentsSent     int64            `json:"documents_sent"`
+	DocumentsRejected int64            `json:"documents_rejected"`
+	Batches           int64            `json:"batches"`
+	Bytes             int64            `json:"bytes"`
+	Retries           int64            `json:"retries"`
+}
+
+func (s *IngestStats) Snapshot() StatsSnapshot {
+	snapshot := StatsSnapshot{
+		Directories:       s.Directories.Load(),
+		FilesSeen:         s.FilesSeen.Load(),
+		FilesParsed:       s.FilesParsed.Load(),
+		FilesSkipped:      s.FilesSkipped.Load(),
+		DocumentsSent:     s.DocumentsSent.Load(),
+		DocumentsRejected: s.DocumentsRejected.Load(),
+		Batches:           s.Batches.Load(),
+		Bytes:             s.Bytes.Load(),
+		Retries:           s.Retries.Load(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.skipReasons) > 0 {
+		snapshot.SkipReasons = make(map[string]int64, len(s.skipReasons))
+		for reason, n := range s.skipReasons {
+			snapshot.SkipReasons[reason] = n
+		}
+	}
+	return snapshot
+}
+
+// Resumen de la carga como texto
+func (s *IngestStats) String() string {
+	snapshot := s.Snapshot()
+
+	var sb strings.Builder
+	fmt.Fprintln(&sb, " Folders procesados: ", snapshot.Directories)
+	fmt.Fprintln(&sb, " Archivos encontrados: ", snapshot.FilesSeen)
+	fmt.Fprintln(&sb, " Archivos procesados: ", snapshot.FilesParsed)
+	fmt.Fprintln(&sb, " Archivos omitidos: ", snapshot.FilesSkipped)
+
+	//motivos en orden alfabetico
+	reasons := make([]string, 0, len(snapshot.SkipReasons))
+	for reason := range snapshot.SkipReasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Fprintf(&sb, "   %s: %d\n", reason, snapshot.SkipReasons[reason])
+	}
+
+	fmt.Fprintln(&sb, " Mensajes enviados: ", snapshot.DocumentsSent)
+	fmt.Fprintln(&sb, " Mensajes rechazados: ", snapshot.DocumentsRejected)
+	fmt.Fprintln(&sb, " Lotes enviados: ", snapshot.Batches)
+	fmt.Fprintln(&sb, " Bytes enviados: ", snapshot.Bytes)
+	fmt.Fprintln(&sb, " Reintentos: ", snapshot.Retries)
+	return sb.String()
+}
+
+// Resumen de la carga como JSON
+func (s *IngestStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Snapshot())
+}
@@ -0,0 +1,124 @@
+package ingest
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"zincsearch.com/mailindex/api/helpers"
+)
+
+// variable de ambiente con la cantidad de workers de procesamiento de archivos
+const ParserWorkers string = "ZINC_LOCAL_PARSER_WORKERS"
+
+// Obtiene cantidad de workers de procesamiento de variables de ambiente,
+// por defecto GOMAXPROCS
+func ParserWorkersFromEnv() (int, error) {
+	return helpers.GetEnvInt(ParserWorkers, runtime.GOMAXPROCS(0))
+}
+
+// Recorre root y sus subdirectorios, entregando cada archivo a un pool fijo de
+// workers que ejecutan parse. La cantidad de goroutines no depende de la forma
+// del arbol de directorios. Termina cuando todos los archivos fueron procesados o
+// al cancelarse el contexto. Las rutas que no pueden leerse (subdirectorios sin
+// permisos) se entregan a fail y el recorrido continua; solo un error al leer
+// root lo termina.
+// Los subdirectorios (sin incluir root) y archivos encontrados se acumulan en stats
+func Walk(ctx context.Context, root string, workers int, stats *IngestStats, parse func(path string), fail func(path string, err error)) error {
+	paths, wait := parserPool(workers, parse)
+	err := walkDir(ctx, root, paths, stats, fail)
+	close(paths)
+	wait()
+
+	return err
+}
+
+// Entrega cada archivo de la lista al pool de workers que ejecutan parse, igual
+// que Walk pero sin recorrer directorios. Un directorio de la lista, por ejemplo
+// uno que no pudo leerse en una carga anterior, se recorre como en Walk.
+// Los archivos se acumulan en stats
+func WalkFiles(ctx context.Context, files []string, workers int, stats *IngestStats, parse func(path string), fail func(path string, err error)) error {
+	paths, wait := parserPool(workers, parse)
+	defer wait()
+	defer close(paths)
+
+	for _, path := range files {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			if err = walkDir(ctx, path, paths, stats, fail); err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+				fail(path, err)
+			}
+			continue
+		}
+
+		stats.FilesSeen.Add(1)
+		select {
+		case paths <- path:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// recorre root entregando sus archivos a paths
+func walkDir(ctx context.Context, root string, paths chan<- string, stats *IngestStats, fail func(path string, err error)) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if path == root {
+				return err
+			}
+			fail(path, err)
+			//directorio que no pudo leerse, se omite su contenido
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			if path != root {
+				stats.Directories.Add(1)
+			}
+			return nil
+		}
+
+		stats.FilesSeen.Add(1)
+		select {
+		case paths <- path:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// inicia workers que ejecutan parse con cada ruta recibida; al cerrar la cola,
+// wait espera que terminen
+func parserPool(workers int, parse func(path string)) (paths chan string, wait func()) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	paths = make(chan string, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				parse(path)
+			}
+		}()
+	}
+	return paths, wg.Wait
+}
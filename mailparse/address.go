@@ -0,0 +1,140 @@
+package mailparse
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// Direccion de correo de un header From, To, Cc o Bcc
+type Address struct {
+	Name string `json:"name,omitempty"`
+	//direccion en minusculas, vacia si el header solo incluye el nombre
+	Address string `json:"address,omitempty"`
+	//dominio de la direccion, en minusculas
+	Domain string `json:"domain,omitempty"`
+}
+
+// interpreta encoded words en los nombres
+var addressParser = &mail.AddressParser{WordDecoder: wordDecoder}
+
+// Interpreta una lista de direcciones. Si la lista no cumple RFC 5322 (nombres
+// con puntos o comas sin comillas, direcciones sin <>, separador ;) se separa por
+// comas y cada elemento se interpreta por separado. Los elementos sin direccion
+// seguidos de uno de la forma Nombre <direccion> son parte de su nombre
+// (Allen, Phillip K. <pallen@enron.com>); los demas se conservan solo con nombre
+func ParseAddressList(value string) []Address {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	if list, err := addressParser.ParseList(value); err == nil {
+		addresses := make([]Address, 0, len(list))
+		for _, addr := range list {
+			addresses = append(addresses, newAddress(addr.Name, addr.Address))
+		}
+		return addresses
+	}
+
+	var addresses []Address
+	add := func(item string) {
+		if addr, ok := parseAddress(item); ok {
+			addresses = append(addresses, addr)
+		}
+	}
+
+	//elementos sin direccion, aun no asignados
+	var names []string
+	for _, item := range splitAddressList(value) {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+		case !strings.Contains(item, "@"):
+			names = append(names, item)
+		case strings.Contains(item, "<") && len(names) > 0:
+			add(strings.Join(append(names, item), ", "))
+			names = nil
+		default:
+			for _, name := range names {
+				add(name)
+			}
+			names = nil
+			add(item)
+		}
+	}
+	for _, name := range names {
+		add(name)
+	}
+	return addresses
+}
+
+// interpreta un elemento de una lista mal formada
+func parseAddress(item string) (Address, bool) {
+	item = strings.TrimSpace(item)
+	if item == "" {
+		return Address{}, false
+	}
+	if addr, err := addressParser.Parse(item); err == nil {
+		return newAddress(addr.Name, addr.Address), true
+	}
+
+	name := item
+	address := ""
+
+	//Nombre <direccion>
+	if start := strings.LastIndex(item, "<"); start >= 0 {
+		if end := strings.Index(item[start:], ">"); end > 0 {
+			address = item[start+1 : start+end]
+			name = item[:start] + item[start+end+1:]
+		}
+	} else {
+		//direccion seguida del nombre: juan@enron.com (Juan)
+		for _, field := range strings.Fields(item) {
+			if strings.Contains(field, "@") {
+				address = field
+				name = strings.Replace(item, field, "", 1)
+				break
+			}
+		}
+	}
+
+	name = strings.Trim(strings.TrimSpace(name), `"'()`)
+	address = strings.Trim(strings.TrimSpace(address), `"'<>.`)
+	if name == "" && address == "" {
+		return Address{}, false
+	}
+	return newAddress(DecodeHeader(name), address), true
+}
+
+func newAddress(name string, address string) Address {
+	addr := Address{Name: strings.TrimSpace(name), Address: strings.ToLower(address)}
+	if at := strings.LastIndex(addr.Address, "@"); at >= 0 {
+		addr.Domain = addr.Address[at+1:]
+	}
+	//algunos clientes repiten la direccion como nombre
+	if strings.EqualFold(strings.Trim(addr.Name, `"'`), addr.Address) {
+		addr.Name = ""
+	}
+	return addr
+}
+
+// separa por comas o punto y coma fuera de comillas y <>
+func splitAddressList(value string) (items []string) {
+	quoted := false
+	angle := 0
+	start := 0
+	for i, r := range value {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == '<' && !quoted:
+			angle++
+		case r == '>' && !quoted && angle > 0:
+			angle--
+		case (r == ',' || r == ';') && !quoted && angle == 0:
+			items = append(items, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, value[start:])
+}
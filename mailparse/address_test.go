@@ -0,0 +1,87 @@
+package mailparse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAddressList(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []Address
+	}{
+		{
+			name:  "rfc 5322",
+			value: `"Allen, Phillip K." <PAllen@Enron.com>, john@x.com`,
+			want: []Address{
+				{Name: "Allen, Phillip K.", Address: "pallen@enron.com", Domain: "enron.com"},
+				{Address: "john@x.com", Domain: "x.com"},
+			},
+		},
+		{
+			name:  "nombre con coma sin comillas",
+			value: "Allen, Phillip K. <pallen@enron.com>, john@x.com",
+			want: []Address{
+				{Name: "Allen, Phillip K.", Address: "pallen@enron.com", Domain: "enron.com"},
+				{Address: "john@x.com", Domain: "x.com"},
+			},
+		},
+		{
+			name:  "varios nombres con coma",
+			value: "Allen, Phillip K. <pallen@enron.com>, Lay, Kenneth <klay@enron.com>",
+			want: []Address{
+				{Name: "Allen, Phillip K.", Address: "pallen@enron.com", Domain: "enron.com"},
+				{Name: "Lay, Kenneth", Address: "klay@enron.com", Domain: "enron.com"},
+			},
+		},
+		{
+			name:  "separador punto y coma",
+			value: "Phillip K. Allen <pallen@enron.com>; john@x.com",
+			want: []Address{
+				{Name: "Phillip K. Allen", Address: "pallen@enron.com", Domain: "enron.com"},
+				{Address: "john@x.com", Domain: "x.com"},
+			},
+		},
+		{
+			name:  "direccion seguida del nombre",
+			value: "pallen@enron.com (Phillip K. Allen), john@x.com.",
+			want: []Address{
+				{Name: "Phillip K. Allen", Address: "pallen@enron.com", Domain: "enron.com"},
+				{Address: "john@x.com", Domain: "x.com"},
+			},
+		},
+		{
+			name:  "solo nombres",
+			value: "Jeff Skilling, Kenneth Lay",
+			want:  []Address{{Name: "Jeff Skilling"}, {Name: "Kenneth Lay"}},
+		},
+		{
+			name:  "nombre sin direccion antes de direccion sin <>",
+			value: "Jeff Skilling, klay@enron.com",
+			want:  []Address{{Name: "Jeff Skilling"}, {Address: "klay@enron.com", Domain: "enron.com"}},
+		},
+		{
+			name:  "nombre repetido como direccion",
+			value: `"john@x.com" <John@X.com>`,
+			want:  []Address{{Address: "john@x.com", Domain: "x.com"}},
+		},
+		{
+			name:  "encoded word",
+			value: "=?iso-8859-1?Q?Jos=E9_L=F3pez?= <jose@x.com>",
+			want:  []Address{{Name: "José López", Address: "jose@x.com", Domain: "x.com"}},
+		},
+		{
+			name:  "vacia",
+			value: "  ",
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseAddressList(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAddressList(%q) = %+v, se espera %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
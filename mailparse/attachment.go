@@ -0,0 +1,110 @@
+package mailparse
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"strings"
+)
+
+// tamaño maximo del texto extraido de un adjunto, en bytes
+const maxAttachmentText int = 1 << 20
+
+// Archivo adjunto de un correo
+type Attachment struct {
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type"`
+	//tamaño del contenido decodificado, en bytes
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+	//texto de adjuntos txt, csv, html y eml
+	Text string `json:"text,omitempty"`
+
+	content []byte
+}
+
+// Contenido decodificado del adjunto
+func (a *Attachment) Content() []byte {
+	return a.content
+}
+
+// Obtiene los archivos adjuntos del correo: partes marcadas como adjunto y
+// partes que no son texto (imagenes, documentos, correos adjuntos). Las partes
+// de texto que forman el cuerpo del correo no se incluyen
+func (p *Part) Attachments() (attachments []Attachment) {
+	if len(p.Parts) > 0 {
+		for _, child := range p.Parts {
+			attachments = append(attachments, child.Attachments()...)
+		}
+		return attachments
+	}
+
+	if !p.IsAttachment() && (p.MediaType == "text/plain" || p.MediaType == "text/html") {
+		return nil
+	}
+
+	sum := sha256.Sum256(p.Content)
+	return []Attachment{{
+		Filename:    p.Filename,
+		ContentType: p.MediaType,
+		Size:        len(p.Content),
+		SHA256:      hex.EncodeToString(sum[:]),
+		Text:        p.attachmentText(),
+		content:     p.Content,
+	}}
+}
+
+// texto de adjuntos txt, csv, html y eml segun su tipo o extension
+func (p *Part) attachmentText() string {
+	kind := p.MediaType
+	switch strings.ToLower(path.Ext(p.Filename)) {
+	case ".txt", ".csv", ".log":
+		kind = "text/plain"
+	case ".htm", ".html":
+		kind = "text/html"
+	case ".eml":
+		kind = "message/rfc822"
+	}
+
+	var text string
+	switch kind {
+	case "text/plain", "text/csv":
+		text = ToUTF8(p.Content, p.Params["charset"])
+	case "text/html":
+		text = HTMLToText(ToUTF8(p.Content, p.Params["charset"]))
+	case "message/rfc822":
+		text = messageText(p.Content)
+	}
+
+	text = strings.TrimSpace(text)
+	if len(text) > maxAttachmentText {
+		//corta en el inicio de un caracter UTF-8
+		cut := maxAttachmentText
+		for cut > 0 && text[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		text = text[:cut]
+	}
+	return text
+}
+
+// asunto y texto de un correo adjunto
+func messageText(content []byte) string {
+	msg, err := mail.ReadMessage(bytes.NewReader(content))
+	if err != nil {
+		return ToUTF8(content, "")
+	}
+	body, err := ParseMIME(textproto.MIMEHeader(msg.Header), msg.Body)
+	if err != nil {
+		return ""
+	}
+
+	subject := DecodeHeader(msg.Header.Get("Subject"))
+	if subject == "" {
+		return body.Text()
+	}
+	return subject + "\n\n" + body.Text()
+}
@@ -0,0 +1,94 @@
+package mailparse
+
+import (
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// decodificador de encoded words (RFC 2047), acepta cualquier charset
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		content, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(ToUTF8(content, charset)), nil
+	},
+}
+
+// Decodifica encoded words (=?iso-8859-1?Q?...?=) de un header. Un header mal
+// codificado se retorna sin decodificar, convertido a UTF-8 valido
+func DecodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return ToUTF8([]byte(value), "")
+	}
+	return ToUTF8([]byte(decoded), "")
+}
+
+// Convierte texto del charset indicado a UTF-8.
+//
+// Los charsets se reconocen por sus nombres y alias en el estandar de
+// codificaciones de HTML (iso-8859-2, koi8-r, shift_jis, utf-16, etc.); como en
+// los navegadores, iso-8859-1 y us-ascii se convierten como windows-1252.
+// Un charset no declarado, desconocido o utf-8 con contenido invalido se
+// convierte como windows-1252. El contenido que ya es UTF-8 valido se conserva
+// si se declara un charset compatible con ASCII: es poco probable que texto
+// iso-8859-x real forme secuencias UTF-8 validas, y es comun que se declare
+// un charset incorrecto
+func ToUTF8(content []byte, charset string) string {
+	enc, name := lookupCharset(charset)
+	switch {
+	case enc == nil || name == "utf-8":
+		if utf8.Valid(content) {
+			return string(content)
+		}
+		enc = charmap.Windows1252
+	case utf8.Valid(content) && asciiCompatible(name):
+		return string(content)
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(content)
+	if err != nil {
+		decoded, _ = charmap.Windows1252.NewDecoder().Bytes(content)
+	}
+	return string(decoded)
+}
+
+// obtiene codificacion y nombre canonico del charset, nil si no se reconoce
+func lookupCharset(charset string) (encoding.Encoding, string) {
+	charset = strings.ToLower(strings.Trim(strings.TrimSpace(charset), `"'`))
+	if charset == "" {
+		return nil, ""
+	}
+
+	//utf-16 sin indicar orden de bytes es big endian salvo que el BOM indique
+	//otro (RFC 2781), en HTML se asume little endian
+	if charset == "utf-16" || charset == "utf16" {
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM), "utf-16"
+	}
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, ""
+	}
+	name, err := htmlindex.Name(enc)
+	//charsets inseguros como hz-gb-2312 se reemplazan por U+FFFD en HTML
+	if err != nil || name == "replacement" {
+		return nil, ""
+	}
+	return enc, name
+}
+
+// indica si el charset codifica ASCII con los mismos bytes y sin secuencias de
+// escape, por lo que un texto en ese charset rara vez es UTF-8 valido
+func asciiCompatible(name string) bool {
+	return !strings.HasPrefix(name, "utf-16") && !strings.HasPrefix(name, "iso-2022")
+}
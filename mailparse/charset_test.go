@@ -0,0 +1,65 @@
+package mailparse
+
+import "testing"
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		charset string
+		want    string
+	}{
+		{"utf-8", "Año ñandú €", "utf-8", "Año ñandú €"},
+		{"utf-8 invalido", "A\xf1o", "utf-8", "Año"},
+		{"sin charset", "caf\xe9 \x93hola\x94", "", "café “hola”"},
+		{"sin charset utf-8", "café", "", "café"},
+		{"charset desconocido", "caf\xe9", "x-desconocido", "café"},
+		{"iso-8859-1 como windows-1252", "\x93caf\xe9\x94", "iso-8859-1", "“café”"},
+		{"windows-1252", "\x80 \x96 \x85", "windows-1252", "€ – …"},
+		{"us-ascii", "caf\xe9", "us-ascii", "café"},
+		{"iso-8859-15", "\xa4 \xbd", "ISO-8859-15", "€ œ"},
+		{"iso-8859-2", "\xb1\xe6\xea", "iso-8859-2", "ąćę"},
+		{"koi8-r", "\xf0\xd2\xc9\xd7\xc5\xd4", "koi8-r", "Привет"},
+		{"windows-1251", "\xcf\xf0\xe8\xe2\xe5\xf2", "cp1251", "Привет"},
+		{"shift_jis", "\x93\xfa\x96\x7b", "shift_jis", "日本"},
+		{"iso-2022-jp", "\x1b$BF|K\\\x1b(B", "iso-2022-jp", "日本"},
+		{"charset entre comillas", "\xb1", `"iso-8859-2"`, "ą"},
+		{"utf-8 declarado como iso-8859-1", "café", "iso-8859-1", "café"},
+		{"utf-16 big endian", "\x00H\x00o\x00l\x00a\x00 \x00\xf1", "utf-16be", "Hola ñ"},
+		{"utf-16 little endian", "H\x00o\x00l\x00a\x00", "utf-16le", "Hola"},
+		{"utf-16 con BOM", "\xff\xfeH\x00o\x00l\x00a\x00", "utf-16", "Hola"},
+		{"utf-16 sin BOM", "\x00H\x00o\x00l\x00a", "UTF-16", "Hola"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToUTF8([]byte(tt.content), tt.charset); got != tt.want {
+				t.Errorf("ToUTF8(%q, %q) = %q, se espera %q", tt.content, tt.charset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeHeader(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Reuni=C3=B3n", "Reuni=C3=B3n"},
+		{"=?utf-8?Q?Reuni=C3=B3n?=", "Reunión"},
+		{"=?iso-8859-1?Q?Jos=E9?= Lopez", "José Lopez"},
+		{"=?windows-1252?Q?=93cita=94?=", "“cita”"},
+		{"=?iso-8859-2?Q?=B1?=", "ą"},
+		{"=?koi8-r?Q?=F0=D2=C9=D7=C5=D4?=", "Привет"},
+		{"=?koi8-r?B?8NLJ18XU?=", "Привет"},
+		{"=?utf-16be?B?AEgAbwBsAGE=?=", "Hola"},
+		{"=?iso-8859-2?Q?=B1?= =?iso-8859-2?Q?=E6?=", "ąć"},
+		//sin decodificar, convertido a UTF-8 valido
+		{"Caf\xe9", "Café"},
+		{"=?x-desconocido?Q?caf=E9?=", "café"},
+	}
+	for _, tt := range tests {
+		if got := DecodeHeader(tt.value); got != tt.want {
+			t.Errorf("DecodeHeader(%q) = %q, se espera %q", tt.value, got, tt.want)
+		}
+	}
+}
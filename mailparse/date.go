@@ -0,0 +1,251 @@
+package mailparse
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Origen de la fecha de un correo
+const (
+	//header Date
+	DateSourceHeader string = "date"
+	//ultimo header Received, agregado por el primer servidor que recibio el correo
+	DateSourceReceived string = "received"
+	//fecha de modificacion del archivo
+	DateSourceModTime string = "mtime"
+)
+
+// ErrNoDate se retorna cuando ninguna fuente permite obtener la fecha del correo
+var ErrNoDate = errors.New("mailparse: fecha no encontrada")
+
+// formatos aceptados, despues de normalizar la fecha: sin comentarios, dia de la
+// semana ni comas, y con la zona horaria como diferencia numerica (-0700)
+var dateLayouts = []string{
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 Jan 06 15:04:05",
+	"2 Jan 06 15:04",
+	"2 January 2006 15:04:05",
+	"Jan 2 2006 15:04:05 -0700",
+	"Jan 2 2006 15:04:05",
+	"Jan 2 2006 3:04:05 PM",
+	"Jan 2 15:04:05 -0700 2006",
+	"Jan 2 15:04:05 2006",
+	"January 2 2006 15:04:05 -0700",
+	"January 2 2006 15:04:05",
+	"January 2 2006 3:04 PM",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"1/2/2006 15:04:05 -0700",
+	"1/2/2006 15:04:05",
+	"1/2/2006 3:04:05 PM",
+	"1/2/2006 3:04 PM",
+	"1/2/2006 15:04",
+	"1/2/06 15:04:05",
+	"1/2/06 3:04 PM",
+	"1/2/2006",
+	"2 Jan 2006",
+}
+
+// diferencia en minutos de abreviaturas de zona horaria comunes
+var zoneOffsets = map[string]int{
+	"UT": 0, "UTC": 0, "GMT": 0, "Z": 0, "WET": 0,
+	"BST": 60, "IST": 60, "WEST": 60, "CET": 60, "MET": 60,
+	"CEST": 120, "MEST": 120, "EET": 120,
+	"EEST": 180, "MSK": 180,
+	"JST": 540, "KST": 540,
+	"AEST": 600, "AEDT": 660,
+	"NZST": 720, "NZDT": 780,
+	"AST": -240, "ADT": -180,
+	"EST": -300, "EDT": -240,
+	"CST": -360, "CDT": -300,
+	"MST": -420, "MDT": -360,
+	"PST": -480, "PDT": -420,
+	"AKST": -540, "AKDT": -480,
+	"HST": -600,
+}
+
+var weekdays = map[string]bool{
+	"mon": true, "tue": true, "wed": true, "thu": true, "fri": true, "sat": true, "sun": true,
+	"monday": true, "tuesday": true, "wednesday": true, "thursday": true, "friday": true, "saturday": true, "sunday": true,
+	"tues": true, "thur": true, "thurs": true,
+}
+
+// Interpreta fecha de un correo aceptando formatos fuera de RFC 5322: comentarios
+// como (PDT), años de dos digitos, abreviaturas de zona horaria, fechas sin zona
+// horaria (se asume UTC) y otros formatos comunes. Fechas anteriores a 1970 se
+// consideran invalidas
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, ErrNoDate
+	}
+
+	//las abreviaturas de zona horaria se reemplazan antes de mail.ParseDate,
+	//que las interpreta con diferencia 0 si no corresponden a la zona local
+	normalized := normalizeDate(value)
+	if date, err := mail.ParseDate(normalized); err == nil && plausible(date) {
+		return date, nil
+	}
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, normalized); err == nil && plausible(date) {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("mailparse: fecha invalida %q", value)
+}
+
+// Obtiene la fecha del correo del header Date; si no existe o es invalida, del
+// header Received mas antiguo y por ultimo de modTime, si no es cero.
+// Retorna ademas el origen de la fecha
+func MessageDate(header mail.Header, modTime time.Time) (date time.Time, source string, err error) {
+	if date, err = ParseDate(header.Get("Date")); err == nil {
+		return date, DateSourceHeader, nil
+	}
+
+	//cada servidor agrega su header Received al inicio, el ultimo es el mas antiguo
+	received := header["Received"]
+	for i := len(received) - 1; i >= 0; i-- {
+		//la fecha sigue al ultimo ;
+		pos := strings.LastIndex(received[i], ";")
+		if pos < 0 {
+			continue
+		}
+		if date, errReceived := ParseDate(received[i][pos+1:]); errReceived == nil {
+			return date, DateSourceReceived, nil
+		}
+	}
+
+	if !modTime.IsZero() {
+		return modTime, DateSourceModTime, nil
+	}
+	return time.Time{}, "", err
+}
+
+func plausible(date time.Time) bool {
+	return date.Year() >= 1970
+}
+
+// elimina comentarios, dia de la semana y comas, y reemplaza abreviaturas de zona
+// horaria por su diferencia numerica
+func normalizeDate(value string) string {
+	value = stripComments(value)
+	value = strings.ReplaceAll(value, ",", " ")
+	fields := strings.Fields(value)
+
+	if len(fields) > 0 && weekdays[strings.ToLower(strings.TrimSuffix(fields[0], "."))] {
+		fields = fields[1:]
+	}
+
+	hasOffset := false
+	for _, field := range fields {
+		if isNumericOffset(field) {
+			hasOffset = true
+		}
+	}
+
+	normalized := fields[:0]
+	for _, field := range fields {
+		upper := strings.ToUpper(field)
+
+		//GMT+0200, UTC-5
+		if len(upper) > 3 && (strings.HasPrefix(upper, "GMT") || strings.HasPrefix(upper, "UTC")) {
+			if offset, ok := parseOffset(upper[3:]); ok && !hasOffset {
+				normalized = append(normalized, offset)
+				hasOffset = true
+			}
+			continue
+		}
+
+		if minutes, ok := zoneOffsets[upper]; ok {
+			if !hasOffset {
+				normalized = append(normalized, formatOffset(minutes))
+				hasOffset = true
+			}
+			continue
+		}
+		normalized = append(normalized, field)
+	}
+	return strings.Join(normalized, " ")
+}
+
+// elimina comentarios entre parentesis, incluyendo anidados
+func stripComments(value string) string {
+	var sb strings.Builder
+	depth := 0
+	for _, r := range value {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case depth == 0:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// +hhmm o -hhmm
+func isNumericOffset(field string) bool {
+	if len(field) != 5 || (field[0] != '+' && field[0] != '-') {
+		return false
+	}
+	for _, c := range field[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// convierte +2, -5, +0200 o -07:00 en +hhmm
+func parseOffset(value string) (string, bool) {
+	if len(value) < 2 || (value[0] != '+' && value[0] != '-') {
+		return "", false
+	}
+	sign := 1
+	if value[0] == '-' {
+		sign = -1
+	}
+
+	digits := strings.ReplaceAll(value[1:], ":", "")
+	hours, minutes := 0, 0
+	for i, c := range digits {
+		if c < '0' || c > '9' {
+			return "", false
+		}
+		//los ultimos dos digitos son minutos solo en +hhmm
+		if len(digits) <= 2 || i < len(digits)-2 {
+			hours = hours*10 + int(c-'0')
+		} else {
+			minutes = minutes*10 + int(c-'0')
+		}
+	}
+	if len(digits) > 4 || hours > 14 || minutes > 59 {
+		return "", false
+	}
+	return formatOffset(sign * (hours*60 + minutes)), true
+}
+
+func formatOffset(minutes int) string {
+	sign := '+'
+	if minutes < 0 {
+		sign = '-'
+		minutes = -minutes
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, minutes/60, minutes%60)
+}
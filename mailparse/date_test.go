@@ -0,0 +1,130 @@
+package mailparse
+
+import (
+	"errors"
+	"net/mail"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	pdt := time.FixedZone("", -7*3600)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"Mon, 14 May 2001 16:39:00 -0700 (PDT)", time.Date(2001, 5, 14, 16, 39, 0, 0, pdt)},
+		{"Mon, 14 May 2001 16:39:00 -0700", time.Date(2001, 5, 14, 16, 39, 0, 0, pdt)},
+		{"Mon, 14 May 2001 16:39:00 PDT", time.Date(2001, 5, 14, 16, 39, 0, 0, pdt)},
+		{"14 May 2001 16:39:00 pdt", time.Date(2001, 5, 14, 16, 39, 0, 0, pdt)},
+		{"Monday, 14 May 2001 16:39 -0700", time.Date(2001, 5, 14, 16, 39, 0, 0, pdt)},
+		{"Mon, 14 May 01 16:39:00 -0700", time.Date(2001, 5, 14, 16, 39, 0, 0, pdt)},
+		{"Fri, 3 Dec 99 08:00:00 EST", time.Date(1999, 12, 3, 8, 0, 0, 0, time.FixedZone("", -5*3600))},
+		{"Mon, 14 May 2001 16:39:00 GMT+2", time.Date(2001, 5, 14, 16, 39, 0, 0, time.FixedZone("", 2*3600))},
+		{"Mon, 14 May 2001 16:39:00 UTC-07:00", time.Date(2001, 5, 14, 16, 39, 0, 0, pdt)},
+		{"Mon, 14 May 2001 16:39:00 -0700 PDT", time.Date(2001, 5, 14, 16, 39, 0, 0, pdt)},
+		//sin zona horaria se asume UTC
+		{"14 May 2001 16:39:00", time.Date(2001, 5, 14, 16, 39, 0, 0, time.UTC)},
+		{"May 14 2001 4:39:00 PM", time.Date(2001, 5, 14, 16, 39, 0, 0, time.UTC)},
+		{"2001-05-14T16:39:00-07:00", time.Date(2001, 5, 14, 16, 39, 0, 0, pdt)},
+		{"2001-05-14 16:39:00", time.Date(2001, 5, 14, 16, 39, 0, 0, time.UTC)},
+		{"5/14/2001 4:39 PM", time.Date(2001, 5, 14, 16, 39, 0, 0, time.UTC)},
+		{"Mon May 14 16:39:00 2001", time.Date(2001, 5, 14, 16, 39, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.value)
+		if err != nil {
+			t.Errorf("ParseDate(%q): %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %v, se espera %v", tt.value, got, tt.want)
+		}
+		_, gotOffset := got.Zone()
+		_, wantOffset := tt.want.Zone()
+		if gotOffset != wantOffset {
+			t.Errorf("ParseDate(%q) diferencia %d, se espera %d", tt.value, gotOffset, wantOffset)
+		}
+	}
+}
+
+func TestParseDateInvalid(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"   ",
+		"garbage",
+		"Mon, 32 May 2001 16:39:00 -0700",
+		"Mon, 14 Foo 2001 16:39:00 -0700",
+		"(PDT)",
+		//anteriores a 1970
+		"Thu, 1 Jan 1960 00:00:00 +0000",
+		"Wed, 31 Dec 69 23:59:59 -0000",
+	} {
+		if got, err := ParseDate(value); err == nil {
+			t.Errorf("ParseDate(%q) = %v, se espera error", value, got)
+		}
+	}
+	if _, err := ParseDate(""); !errors.Is(err, ErrNoDate) {
+		t.Errorf("ParseDate vacio retorna %v, se espera %v", err, ErrNoDate)
+	}
+}
+
+func TestMessageDate(t *testing.T) {
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	received := []string{
+		"from mail2.enron.com by mail3.enron.com; Tue, 15 May 2001 10:00:00 -0500",
+		"from sender.com by mail2.enron.com;\r\n\tMon, 14 May 2001 18:39:00 -0500 (CDT)",
+	}
+
+	tests := []struct {
+		name    string
+		header  mail.Header
+		modTime time.Time
+		want    time.Time
+		source  string
+	}{
+		{
+			name:    "date",
+			header:  mail.Header{"Date": {"Mon, 14 May 2001 16:39:00 -0700 (PDT)"}, "Received": received},
+			modTime: modTime,
+			want:    time.Date(2001, 5, 14, 23, 39, 0, 0, time.UTC),
+			source:  DateSourceHeader,
+		},
+		{
+			name:    "received mas antiguo",
+			header:  mail.Header{"Date": {"garbage"}, "Received": received},
+			modTime: modTime,
+			want:    time.Date(2001, 5, 14, 23, 39, 0, 0, time.UTC),
+			source:  DateSourceReceived,
+		},
+		{
+			name:    "received invalido omitido",
+			header:  mail.Header{"Received": {received[0], "from x by y; no date"}},
+			modTime: modTime,
+			want:    time.Date(2001, 5, 15, 15, 0, 0, 0, time.UTC),
+			source:  DateSourceReceived,
+		},
+		{
+			name:    "mtime",
+			header:  mail.Header{"Date": {"garbage"}, "Received": {"from x by y"}},
+			modTime: modTime,
+			want:    modTime,
+			source:  DateSourceModTime,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, source, err := MessageDate(tt.header, tt.modTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) || source != tt.source {
+				t.Errorf("MessageDate = %v, %q, se espera %v, %q", got, source, tt.want, tt.source)
+			}
+		})
+	}
+
+	if _, _, err := MessageDate(mail.Header{"Date": {"garbage"}}, time.Time{}); err == nil {
+		t.Error("MessageDate sin fecha valida ni mtime no retorna error")
+	}
+}
@@ -0,0 +1,60 @@
+package mailparse
+
+import (
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// Headers a capturar de cada correo, ademas de los campos propios del documento
+type HeaderCapture struct {
+	//todos los headers
+	All bool
+	//nombres en forma canonica (X-From, X-Cc)
+	Names map[string]bool
+}
+
+// Interpreta lista de headers separados por coma, o "all" para capturar todos.
+// Los nombres no distinguen mayusculas (X-cc equivale a X-Cc)
+func ParseHeaderCapture(value string) HeaderCapture {
+	capture := HeaderCapture{Names: map[string]bool{}}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+		case strings.EqualFold(name, "all"):
+			capture.All = true
+		default:
+			capture.Names[textproto.CanonicalMIMEHeaderKey(name)] = true
+		}
+	}
+	return capture
+}
+
+// Indica si no se captura ningun header
+func (c HeaderCapture) Empty() bool {
+	return !c.All && len(c.Names) == 0
+}
+
+// Obtiene los headers configurados, con encoded words decodificadas. Un header
+// repetido (Received) se une en un solo valor separado por saltos de linea
+func (c HeaderCapture) Capture(header mail.Header) map[string]string {
+	if c.Empty() {
+		return nil
+	}
+
+	captured := map[string]string{}
+	for name, values := range header {
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		if !c.All && !c.Names[name] {
+			continue
+		}
+
+		decoded := make([]string, len(values))
+		for i, value := range values {
+			decoded[i] = DecodeHeader(value)
+		}
+		captured[name] = strings.Join(decoded, "\n")
+	}
+	return captured
+}
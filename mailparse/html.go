@@ -0,0 +1,117 @@
+package mailparse
+
+import (
+	"html"
+	"strings"
+)
+
+// etiquetas que inician una nueva linea
+var blockTags = map[string]bool{
+	"br": true, "p": true, "div": true, "tr": true, "li": true, "ul": true, "ol": true,
+	"table": true, "blockquote": true, "pre": true, "hr": true, "title": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// etiquetas cuyo contenido se descarta
+var skipTags = map[string]bool{
+	"script": true, "style": true, "head": true,
+}
+
+// Convierte HTML en texto: elimina etiquetas, comentarios, scripts y estilos,
+// decodifica entidades (&amp;, &nbsp;) y conserva saltos de linea de parrafos,
+// filas y etiquetas <br>
+func HTMLToText(source string) string {
+	var sb strings.Builder
+	skip := ""
+
+	for len(source) > 0 {
+		start := strings.IndexByte(source, '<')
+		if start < 0 {
+			if skip == "" {
+				writeText(&sb, source)
+			}
+			break
+		}
+		if skip == "" {
+			writeText(&sb, source[:start])
+		}
+		source = source[start:]
+
+		//comentario
+		if strings.HasPrefix(source, "<!--") {
+			end := strings.Index(source, "-->")
+			if end < 0 {
+				break
+			}
+			source = source[end+3:]
+			continue
+		}
+
+		end := strings.IndexByte(source, '>')
+		if end < 0 {
+			break
+		}
+		name, closing := tagName(source[1:end])
+		source = source[end+1:]
+
+		switch {
+		case skip != "":
+			if closing && name == skip {
+				skip = ""
+			}
+		case skipTags[name] && !closing:
+			skip = name
+		case blockTags[name]:
+			sb.WriteByte('\n')
+		case name == "td" || name == "th":
+			sb.WriteByte(' ')
+		}
+	}
+
+	return cleanText(html.UnescapeString(sb.String()))
+}
+
+// en HTML los saltos de linea del texto equivalen a espacios
+func writeText(sb *strings.Builder, text string) {
+	for _, r := range text {
+		if r == '\n' || r == '\r' || r == '\t' {
+			r = ' '
+		}
+		sb.WriteRune(r)
+	}
+}
+
+// nombre de la etiqueta en minusculas e indicador de etiqueta de cierre
+func tagName(tag string) (string, bool) {
+	tag = strings.TrimSpace(tag)
+	closing := strings.HasPrefix(tag, "/")
+	tag = strings.TrimPrefix(tag, "/")
+
+	end := strings.IndexAny(tag, " \t\r\n/")
+	if end >= 0 {
+		tag = tag[:end]
+	}
+	return strings.ToLower(tag), closing
+}
+
+// une espacios consecutivos y elimina lineas vacias repetidas
+func cleanText(text string) string {
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+
+	lines := strings.Split(text, "\n")
+	cleaned := make([]string, 0, len(lines))
+	blank := false
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			if !blank && len(cleaned) > 0 {
+				cleaned = append(cleaned, "")
+			}
+			blank = true
+			continue
+		}
+		cleaned = append(cleaned, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(cleaned, "\n"))
+}
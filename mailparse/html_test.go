@@ -0,0 +1,30 @@
+package mailparse
+
+import "testing"
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"texto", "Hola mundo", "Hola mundo"},
+		{"entidades", "Tom &amp; Jerry&nbsp;&lt;3 &eacute;", "Tom & Jerry <3 é"},
+		{"parrafos y br", "<p>Uno</p><p>Dos<br>Tres<BR/>Cuatro</p>", "Uno\n\nDos\nTres\nCuatro"},
+		{"saltos de linea del texto", "Una\nsola\r\n  linea", "Una sola linea"},
+		{"tabla", "<table><tr><td>a</td><td>b</td></tr><tr><th>c</th><td>d</td></tr></table>", "a b\n\nc d"},
+		{"comentarios", "Antes<!-- <p>oculto</p> -->Despues", "AntesDespues"},
+		{"script, style y head", "<html><head><title>T</title></head><style>p{}</style><script>alert('<p>')</script>Cuerpo</html>", "Cuerpo"},
+		{"etiquetas con atributos", `<a href="http://x.com/?a=1&amp;b=2">enlace</a> <font face="Arial" >texto</font>`, "enlace texto"},
+		{"lineas vacias repetidas", "<div>a</div><div></div><div></div><div>b</div>", "a\n\nb"},
+		{"etiqueta sin cerrar", "texto <b", "texto"},
+		{"vacio", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTMLToText(tt.html); got != tt.want {
+				t.Errorf("HTMLToText(%q) = %q, se espera %q", tt.html, got, tt.want)
+			}
+		})
+	}
+}
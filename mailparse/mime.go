@@ -0,0 +1,184 @@
+package mailparse
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+)
+
+// profundidad maxima de partes multipart anidadas
+const maxDepth int = 20
+
+// Parte MIME de un correo. Las partes multipart contienen sus partes en Parts,
+// las demas su contenido en Content, ya decodificado de base64 o quoted-printable
+type Part struct {
+	Header textproto.MIMEHeader
+	//tipo de contenido en minusculas, text/plain si no se indica o es invalido
+	MediaType string
+	//parametros del tipo de contenido: charset, boundary, name
+	Params map[string]string
+	//inline, attachment o vacio
+	Disposition string
+	//nombre del archivo adjunto, de Content-Disposition o del parametro name
+	Filename string
+
+	Content []byte
+	Parts   []*Part
+}
+
+// Interpreta el cuerpo de un correo recorriendo sus partes multipart, incluyendo
+// multipart/alternative y multipart/mixed anidadas. Un multipart mal formado
+// conserva las partes leidas antes del error; si no hay ninguna, el cuerpo se
+// trata como texto
+func ParseMIME(header textproto.MIMEHeader, body io.Reader) (*Part, error) {
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	return parsePart(header, content, 0), nil
+}
+
+func parsePart(header textproto.MIMEHeader, content []byte, depth int) *Part {
+	part := &Part{Header: header, MediaType: "text/plain", Params: map[string]string{}}
+
+	if mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type")); err == nil {
+		part.MediaType = mediaType
+		part.Params = params
+	}
+	if disposition, params, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		part.Disposition = disposition
+		part.Filename = params["filename"]
+	}
+	if part.Filename == "" {
+		part.Filename = part.Params["name"]
+	}
+	//algunos clientes codifican el nombre con encoded words en lugar de RFC 2231
+	part.Filename = DecodeHeader(part.Filename)
+
+	boundary := part.Params["boundary"]
+	if strings.HasPrefix(part.MediaType, "multipart/") && boundary != "" && depth < maxDepth {
+		reader := multipart.NewReader(bytes.NewReader(content), boundary)
+		for {
+			//NextRawPart conserva Content-Transfer-Encoding, se decodifica en parsePart
+			child, err := reader.NextRawPart()
+			if err != nil {
+				break
+			}
+			childContent, err := io.ReadAll(child)
+			if err != nil {
+				break
+			}
+			part.Parts = append(part.Parts, parsePart(child.Header, childContent, depth+1))
+		}
+		if len(part.Parts) > 0 {
+			return part
+		}
+		//sin partes validas se trata como texto
+		part.MediaType = "text/plain"
+	}
+
+	part.Content = decodeTransfer(header.Get("Content-Transfer-Encoding"), content)
+	return part
+}
+
+// decodifica base64 o quoted-printable; ante contenido invalido conserva lo
+// decodificado hasta el error, o el contenido original si no se decodifico nada
+func decodeTransfer(encoding string, content []byte) []byte {
+	var reader io.Reader
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		//ignora saltos de linea y espacios
+		clean := bytes.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+				return -1
+			}
+			return r
+		}, content)
+		reader = base64.NewDecoder(base64.StdEncoding, bytes.NewReader(clean))
+	case "quoted-printable":
+		reader = quotedprintable.NewReader(bytes.NewReader(content))
+	default:
+		return content
+	}
+
+	decoded, err := io.ReadAll(reader)
+	if err != nil && len(decoded) == 0 {
+		return content
+	}
+	return decoded
+}
+
+// Indica si la parte es un archivo adjunto: Content-Disposition attachment, o
+// una parte con nombre de archivo que no se declara inline
+func (p *Part) IsAttachment() bool {
+	if p.Disposition == "attachment" {
+		return true
+	}
+	return p.Filename != "" && p.Disposition != "inline"
+}
+
+// Obtiene el texto del correo. En multipart/alternative se prefiere text/plain
+// sobre text/html; en los demas multipart se concatenan las partes de texto que
+// no son adjuntos. El HTML se convierte a texto
+func (p *Part) Text() string {
+	text, _ := p.text()
+	return text
+}
+
+// texto de la parte e indicador de si proviene de HTML
+func (p *Part) text() (string, bool) {
+	if len(p.Parts) > 0 {
+		if p.MediaType == "multipart/alternative" {
+			return alternativeText(p.Parts)
+		}
+
+		var texts []string
+		fromHTML := false
+		for _, child := range p.Parts {
+			if child.IsAttachment() {
+				continue
+			}
+			if text, html := child.text(); text != "" {
+				texts = append(texts, text)
+				fromHTML = fromHTML || html
+			}
+			//multipart/related: el resto son recursos de la parte principal
+			if p.MediaType == "multipart/related" && len(texts) > 0 {
+				break
+			}
+		}
+		return strings.Join(texts, "\n\n"), fromHTML
+	}
+
+	//el texto se convierte a UTF-8 desde el charset declarado
+	switch p.MediaType {
+	case "text/plain":
+		return strings.TrimSpace(ToUTF8(p.Content, p.Params["charset"])), false
+	case "text/html":
+		return HTMLToText(ToUTF8(p.Content, p.Params["charset"])), true
+	}
+	return "", false
+}
+
+// primera alternativa de texto plano, o la primera HTML si no hay
+func alternativeText(parts []*Part) (string, bool) {
+	htmlText := ""
+	for _, child := range parts {
+		text, html := child.text()
+		if text == "" {
+			continue
+		}
+		if !html {
+			return text, false
+		}
+		if htmlText == "" {
+			htmlText = text
+		}
+	}
+	return htmlText, htmlText != ""
+}
@@ -0,0 +1,182 @@
+package mailparse
+
+import (
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// interpreta un correo completo con saltos de linea \n
+func parseMessage(t *testing.T, raw string) *Part {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(strings.ReplaceAll(raw, "\n", "\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	part, err := ParseMIME(textproto.MIMEHeader(msg.Header), msg.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return part
+}
+
+func TestPartText(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{
+			name: "texto plano sin Content-Type",
+			raw:  "Subject: x\n\n  Hola mundo  \n",
+			want: "Hola mundo",
+		},
+		{
+			name: "quoted-printable latin1",
+			raw: `Content-Type: text/plain; charset="iso-8859-1"
+Content-Transfer-Encoding: quoted-printable
+
+Reuni=F3n ma=F1ana, l=C3=ADnea =
+continuada
+`,
+			want: "Reunión mañana, lÃ­nea continuada",
+		},
+		{
+			name: "base64 con saltos de linea",
+			raw: `Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: base64
+
+UmV1bmnDs24g
+bWHDsWFuYQ==
+`,
+			want: "Reunión mañana",
+		},
+		{
+			name: "base64 invalido conserva lo decodificado",
+			raw: `Content-Transfer-Encoding: base64
+
+SG9sYQ==!!!
+`,
+			want: "Hola",
+		},
+		{
+			name: "alternative prefiere texto plano",
+			raw: `Content-Type: multipart/alternative; boundary="b1"
+
+--b1
+Content-Type: text/html
+
+<p>Version <b>HTML</b></p>
+--b1
+Content-Type: text/plain
+
+Version texto
+--b1--
+`,
+			want: "Version texto",
+		},
+		{
+			name: "alternative solo HTML",
+			raw: `Content-Type: multipart/alternative; boundary="b1"
+
+--b1
+Content-Type: text/html; charset=windows-1252
+Content-Transfer-Encoding: quoted-printable
+
+<p>=93Hola=94 &amp; adi=F3s</p>
+--b1--
+`,
+			want: "“Hola” & adiós",
+		},
+		{
+			name: "mixed con alternative anidado y adjunto",
+			raw: `Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain
+
+Cuerpo
+--inner
+Content-Type: text/html
+
+<p>Cuerpo HTML</p>
+--inner--
+--outer
+Content-Type: text/plain; name="notas.txt"
+Content-Disposition: attachment; filename="notas.txt"
+
+Texto del adjunto
+--outer
+Content-Type: text/plain
+Content-Disposition: inline
+
+Firma
+--outer--
+`,
+			want: "Cuerpo\n\nFirma",
+		},
+		{
+			name: "multipart sin partes validas se trata como texto",
+			raw: `Content-Type: multipart/mixed; boundary="falta"
+
+Texto sin delimitadores
+`,
+			want: "Texto sin delimitadores",
+		},
+		{
+			name: "multipart truncado conserva partes leidas",
+			raw: `Content-Type: multipart/mixed; boundary="b1"
+
+--b1
+Content-Type: text/plain
+
+Primera parte
+--b1
+Content-Type: text/plain
+
+Parte sin cierre`,
+			want: "Primera parte",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMessage(t, tt.raw).Text(); got != tt.want {
+				t.Errorf("Text() = %q, se espera %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMIMEParts(t *testing.T) {
+	part := parseMessage(t, `Content-Type: multipart/mixed; boundary="b1"
+
+--b1
+Content-Type: text/plain
+
+Cuerpo
+--b1
+Content-Type: application/pdf; name="=?utf-8?Q?informe_a=C3=B1o.pdf?="
+Content-Transfer-Encoding: base64
+
+JVBERi0xLjQ=
+--b1--
+`)
+	if part.MediaType != "multipart/mixed" || len(part.Parts) != 2 {
+		t.Fatalf("MediaType = %q con %d partes, se espera multipart/mixed con 2", part.MediaType, len(part.Parts))
+	}
+
+	pdf := part.Parts[1]
+	if pdf.MediaType != "application/pdf" || pdf.Filename != "informe año.pdf" || !pdf.IsAttachment() {
+		t.Errorf("adjunto %q %q, adjunto %v", pdf.MediaType, pdf.Filename, pdf.IsAttachment())
+	}
+	if string(pdf.Content) != "%PDF-1.4" {
+		t.Errorf("Content = %q, se espera %q", pdf.Content, "%PDF-1.4")
+	}
+	if part.Parts[0].IsAttachment() {
+		t.Error("el cuerpo de texto se considera adjunto")
+	}
+}
@@ -0,0 +1,58 @@
+package mailparse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Almacen en disco de adjuntos direccionado por contenido: cada adjunto se guarda
+// una sola vez en dir/ab/cd/<sha256>, donde ab y cd son los primeros caracteres
+// del SHA-256, sin importar cuantos correos lo incluyan
+type AttachmentStore struct {
+	dir string
+}
+
+// Crea almacen de adjuntos, creando el directorio si no existe
+func NewAttachmentStore(dir string) (*AttachmentStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &AttachmentStore{dir: dir}, nil
+}
+
+// Ruta del adjunto con el SHA-256 indicado
+func (s *AttachmentStore) Path(sha256 string) string {
+	return filepath.Join(s.dir, sha256[:2], sha256[2:4], sha256)
+}
+
+// Guarda el contenido del adjunto si aun no existe. La escritura utiliza un
+// archivo temporal y rename, un adjunto nunca queda incompleto
+func (s *AttachmentStore) Save(attachment *Attachment) error {
+	target := s.Path(attachment.SHA256)
+	if _, err := os.Stat(target); err == nil {
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, attachment.SHA256+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(attachment.content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
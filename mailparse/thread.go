@@ -0,0 +1,147 @@
+package mailparse
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// prefijo del ThreadID calculado a partir del asunto
+const subjectThreadPrefix string = "subject:"
+
+// prefijos de respuesta y reenvio en distintos idiomas
+var replyPrefixes = []string{"re", "fw", "fwd", "aw", "wg", "sv", "vs", "tr", "rv", "enc", "res"}
+
+// Datos de hilo de conversacion de un correo
+type Thread struct {
+	MessageID  string
+	InReplyTo  string
+	References []string
+	//raiz de la conversacion, compartida por todos los correos del hilo
+	ThreadID string
+}
+
+// Obtiene los ids de hilo de los headers Message-ID, In-Reply-To y References.
+//
+// Como en el algoritmo de JWZ, la raiz del hilo es el primer id de References; si
+// no existe, el de In-Reply-To, y si el correo no responde a otro, su propio
+// Message-ID. Una respuesta (Re:, Fwd:) sin References ni In-Reply-To se agrupa
+// por el asunto normalizado, con el prefijo "subject:".
+//
+// Cada correo se procesa por separado: en una cadena solo con In-Reply-To
+// (A <- B <- C) el ThreadID de C es el id de B, no la raiz A
+func ParseThread(header mail.Header, subject string) Thread {
+	thread := Thread{
+		MessageID:  NormalizeMessageID(header.Get("Message-ID")),
+		References: ParseMessageIDs(header.Get("References")),
+	}
+	//In-Reply-To puede incluir texto libre ademas del id
+	if inReplyTo := ParseMessageIDs(header.Get("In-Reply-To")); len(inReplyTo) > 0 {
+		thread.InReplyTo = inReplyTo[0]
+	}
+
+	normalized, reply := NormalizeSubject(subject)
+	switch {
+	case len(thread.References) > 0:
+		thread.ThreadID = thread.References[0]
+	case thread.InReplyTo != "":
+		thread.ThreadID = thread.InReplyTo
+	case reply && normalized != "":
+		thread.ThreadID = subjectThreadPrefix + normalized
+	default:
+		thread.ThreadID = thread.MessageID
+	}
+	return thread
+}
+
+// Obtiene el id de un header Message-ID, sin los delimitadores <>.
+// Sin delimitadores se utiliza la primera palabra
+func NormalizeMessageID(value string) string {
+	if ids := ParseMessageIDs(value); len(ids) > 0 {
+		return ids[0]
+	}
+	if fields := strings.Fields(stripComments(value)); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// Obtiene los ids de un header References o In-Reply-To en orden, sin los
+// delimitadores <>. Sin delimitadores solo se aceptan palabras con @.
+// Los ids repetidos se omiten
+func ParseMessageIDs(value string) (ids []string) {
+	value = stripComments(value)
+	seen := map[string]bool{}
+	add := func(id string) {
+		id = strings.TrimSpace(id)
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if !strings.Contains(value, "<") {
+		for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\r' || r == '\n' }) {
+			if strings.Contains(field, "@") {
+				add(field)
+			}
+		}
+		return ids
+	}
+
+	for {
+		start := strings.Index(value, "<")
+		if start < 0 {
+			return ids
+		}
+		end := strings.Index(value[start:], ">")
+		if end < 0 {
+			return ids
+		}
+		add(value[start+1 : start+end])
+		value = value[start+end+1:]
+	}
+}
+
+// Normaliza asunto para agrupar conversaciones: elimina prefijos de respuesta y
+// reenvio (Re:, RE[2]:, Fwd:, AW:) y etiquetas [lista], y convierte a minusculas.
+// Indica ademas si el asunto tenia prefijo de respuesta o reenvio
+func NormalizeSubject(subject string) (normalized string, reply bool) {
+	subject = strings.TrimSpace(subject)
+	for {
+		trimmed := strings.TrimSpace(subject)
+
+		//etiqueta de lista de correo
+		if strings.HasPrefix(trimmed, "[") {
+			if end := strings.Index(trimmed, "]"); end > 0 {
+				trimmed = trimmed[end+1:]
+			}
+		}
+
+		if rest, ok := trimReplyPrefix(trimmed); ok {
+			reply = true
+			trimmed = rest
+		}
+
+		if trimmed == subject {
+			break
+		}
+		subject = trimmed
+	}
+	return strings.ToLower(strings.Join(strings.Fields(subject), " ")), reply
+}
+
+// elimina un prefijo de respuesta: re:, re[2]:, re(2):, re2:
+func trimReplyPrefix(subject string) (string, bool) {
+	colon := strings.Index(subject, ":")
+	if colon <= 0 || colon > 8 {
+		return subject, false
+	}
+	prefix := strings.ToLower(strings.TrimSpace(subject[:colon]))
+	prefix = strings.TrimRight(prefix, "0123456789[]() ")
+	for _, p := range replyPrefixes {
+		if prefix == p {
+			return strings.TrimSpace(subject[colon+1:]), true
+		}
+	}
+	return subject, false
+}
@@ -0,0 +1,138 @@
+package mailparse
+
+import (
+	"net/mail"
+	"reflect"
+	"testing"
+)
+
+func TestParseThreadSeparatesThreads(t *testing.T) {
+	//dos conversaciones distintas con el mismo asunto
+	tests := []struct {
+		header mail.Header
+		want   string
+	}{
+		{mail.Header{"Message-Id": {"<a1@enron.com>"}, "Subject": {"Meeting"}}, "a1@enron.com"},
+		{mail.Header{"Message-Id": {"<a2@enron.com>"}, "Subject": {"RE: Meeting"}, "References": {"<a1@enron.com>"}}, "a1@enron.com"},
+		//asunto editado, se conserva el hilo de References
+		{mail.Header{"Message-Id": {"<a3@enron.com>"}, "Subject": {"Re: Meeting moved to 3pm"}, "References": {"<a1@enron.com> <a2@enron.com>"}, "In-Reply-To": {"<a2@enron.com>"}}, "a1@enron.com"},
+		{mail.Header{"Message-Id": {"<b1@enron.com>"}, "Subject": {"Meeting"}}, "b1@enron.com"},
+		{mail.Header{"Message-Id": {"<b2@enron.com>"}, "Subject": {"Re: Meeting"}, "In-Reply-To": {"<b1@enron.com>"}}, "b1@enron.com"},
+		//respuesta sin headers de hilo, se agrupa por asunto
+		{mail.Header{"Message-Id": {"<c1@enron.com>"}, "Subject": {"[energy] Re: RE: Meeting"}}, "subject:meeting"},
+	}
+	for _, tt := range tests {
+		thread := ParseThread(tt.header, tt.header.Get("Subject"))
+		if thread.ThreadID != tt.want {
+			t.Errorf("%s: ThreadID = %q, se espera %q", thread.MessageID, thread.ThreadID, tt.want)
+		}
+	}
+}
+
+func TestParseThreadHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		header mail.Header
+		want   Thread
+	}{
+		{
+			name: "references",
+			header: mail.Header{
+				"Message-Id":  {"<c@x>"},
+				"In-Reply-To": {"<b@x>"},
+				"References":  {"<a@x>\r\n <b@x> <a@x>"},
+			},
+			want: Thread{MessageID: "c@x", InReplyTo: "b@x", References: []string{"a@x", "b@x"}, ThreadID: "a@x"},
+		},
+		{
+			name: "in-reply-to con texto libre",
+			header: mail.Header{
+				"Message-Id":  {"<b@x>"},
+				"In-Reply-To": {`Your message of "Mon, 14 May 2001" <a@x>`},
+			},
+			want: Thread{MessageID: "b@x", InReplyTo: "a@x", ThreadID: "a@x"},
+		},
+		{
+			name:   "correo original",
+			header: mail.Header{"Message-Id": {"<a@x> (comentario)"}},
+			want:   Thread{MessageID: "a@x", ThreadID: "a@x"},
+		},
+		{
+			name:   "sin headers",
+			header: mail.Header{},
+			want:   Thread{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseThread(tt.header, ""); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseThread = %+v, se espera %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeMessageID(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"<abc@x>", "abc@x"},
+		{"  <abc@x>  ", "abc@x"},
+		{"<abc@x> (comment)", "abc@x"},
+		{"(comment) <abc@x>", "abc@x"},
+		{"abc@x", "abc@x"},
+		{"abc@x (comment)", "abc@x"},
+		{"", ""},
+		{"   ", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeMessageID(tt.value); got != tt.want {
+			t.Errorf("NormalizeMessageID(%q) = %q, se espera %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseMessageIDs(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"<a@x> <b@x>", []string{"a@x", "b@x"}},
+		{"<a@x>,<b@x>,<a@x>", []string{"a@x", "b@x"}},
+		{"a@x, b@x palabra", []string{"a@x", "b@x"}},
+		{"<a@x> (comentario <c@x>)", []string{"a@x"}},
+		{"<a@x", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := ParseMessageIDs(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseMessageIDs(%q) = %q, se espera %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeSubject(t *testing.T) {
+	tests := []struct {
+		subject    string
+		normalized string
+		reply      bool
+	}{
+		{"Gas prices", "gas prices", false},
+		{"RE: Gas prices", "gas prices", true},
+		{"Re[2]: Re: Gas   prices ", "gas prices", true},
+		{"Fwd: RE(3): gas prices", "gas prices", true},
+		{"AW: WG: Angebot", "angebot", true},
+		{"[enron-list] Re: Meeting", "meeting", true},
+		{"Re: [enron-list] Meeting", "meeting", true},
+		{"Note: meeting moved", "note: meeting moved", false},
+		{"Re:", "", true},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		normalized, reply := NormalizeSubject(tt.subject)
+		if normalized != tt.normalized || reply != tt.reply {
+			t.Errorf("NormalizeSubject(%q) = %q, %v, se espera %q, %v", tt.subject, normalized, reply, tt.normalized, tt.reply)
+		}
+	}
+}
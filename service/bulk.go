@@ -0,0 +1,189 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Acciones de carga bulk NDJSON (/api/_bulk)
+const (
+	BulkActionIndex  string = "index"  //crea o reemplaza documento
+	BulkActionCreate string = "create" //crea documento
+	//reemplaza el documento completo con el id indicado. A diferencia de
+	//Elasticsearch, ZincSearch indexa la linea de documento tal cual: no acepta
+	//{"doc": {...}} ni actualizaciones parciales
+	BulkActionUpdate string = "update"
+	BulkActionDelete string = "delete" //elimina documento
+)
+
+// Operacion sobre un documento dentro de una carga bulk NDJSON
+type BulkOperation struct {
+	Action string
+	//indice destino, vacio utiliza el indice por defecto de la peticion
+	Index string
+	//id del documento, vacio genera id en el servidor (no aplica a update/delete)
+	ID string
+	//documento, se serializa con encoding/json; json.RawMessage se envia sin cambios.
+	//Se omite en delete
+	Doc interface{}
+}
+
+// Escribe operaciones bulk en formato NDJSON: una linea de accion
+// seguida de una linea de documento (excepto delete)
+type BulkEncoder struct {
+	w            io.Writer
+	defaultIndex string
+}
+
+func NewBulkEncoder(w io.Writer, defaultIndex string) *BulkEncoder {
+	return &BulkEncoder{w: w, defaultIndex: defaultIndex}
+}
+
+type bulkActionMeta struct {
+	Index string `json:"_index,omitempty"`
+	ID    string `json:"_id,omitempty"`
+}
+
+func (e *BulkEncoder) Encode(op BulkOperation) error {
+	meta := bulkActionMeta{Index: op.Index, ID: op.ID}
+	if meta.Index == "" {
+		meta.Index = e.defaultIndex
+	}
+
+	switch op.Action {
+	case BulkActionIndex, BulkActionCreate:
+	case BulkActionUpdate, BulkActionDelete:
+		if op.ID == "" {
+			return fmt.Errorf("zincsearch: la accion bulk %q requiere id", op.Action)
+		}
+	default:
+		return fmt.Errorf("zincsearch: accion bulk invalida %q", op.Action)
+	}
+
+	action, err := json.Marshal(map[string]bulkActionMeta{op.Action: meta})
+	if err != nil {
+		return err
+	}
+	if err = e.writeLine(action); err != nil {
+		return err
+	}
+
+	if op.Action == BulkActionDelete {
+		return nil
+	}
+
+	line, err := json.Marshal(op.Doc)
+	if err != nil {
+		return err
+	}
+	return e.writeLine(line)
+}
+
+func (e *BulkEncoder) writeLine(line []byte) error {
+	if _, err := e.w.Write(line); err != nil {
+		return err
+	}
+	_, err := e.w.Write([]byte{'\n'})
+	return err
+}
+
+// Escribe cuerpo de carga _bulkv2 conforme se agregan registros:
+// {"index": "...", "records": [registro, registro, ...]}
+type BulkV2Encoder struct {
+	w       io.Writer
+	index   string
+	records int
+}
+
+func NewBulkV2Encoder(w io.Writer, indexName string) *BulkV2Encoder {
+	return &BulkV2Encoder{w: w, index: indexName}
+}
+
+// agrega registro serializado con encoding/json
+func (e *BulkV2Encoder) Encode(record interface{}) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return e.EncodeRaw(line)
+}
+
+// agrega registro que ya es un objeto JSON valido, se escribe sin cambios
+func (e *BulkV2Encoder) EncodeRaw(record []byte) (err error) {
+	if e.records == 0 {
+		err = e.writeHeader()
+	} else {
+		_, err = e.w.Write([]byte{','})
+	}
+	if err != nil {
+		return err
+	}
+	e.records++
+	_, err = e.w.Write(record)
+	return err
+}
+
+// cierra la estructura JSON, debe invocarse despues del ultimo registro
+func (e *BulkV2Encoder) Close() error {
+	if e.records == 0 {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(e.w, "]}")
+	return err
+}
+
+func (e *BulkV2Encoder) writeHeader() error {
+	index, err := json.Marshal(e.index)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(e.w, `{"index":%s,"records":[`, index)
+	return err
+}
+
+// Ejecuta carga bulk NDJSON, cada operacion puede indicar su propio indice e id
+func (s *ZincSearch) Bulk(ctx context.Context, indexName string, operations []BulkOperation) (result *BulkResponse, err error) {
+	const resource string = "/api/_bulk"
+
+	var buf bytes.Buffer
+	encoder := NewBulkEncoder(&buf, indexName)
+	for _, op := range operations {
+		if err = encoder.Encode(op); err != nil {
+			return result, err
+		}
+	}
+
+	//ejecuta peticion
+	body, err := s.ejecutaPeticion(ctx, http.MethodPost, resource, "", bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return result, err
+	}
+
+	//retorna respuesta
+	result = &BulkResponse{}
+	err = decodeResponse(http.MethodPost, resource, body, result)
+	return result, err
+}
+
+// Ejecuta carga bulk NDJSON generando el cuerpo mientras se envia,
+// normalmente con NewBulkEncoder. En cada reintento write se invoca de nuevo
+func (s *ZincSearch) BulkStream(ctx context.Context, write func(w io.Writer) error) (result *BulkResponse, err error) {
+	const resource string = "/api/_bulk"
+
+	//ejecuta peticion
+	body, err := s.ejecutaPeticionStream(ctx, http.MethodPost, resource, "", write)
+	if err != nil {
+		return result, err
+	}
+
+	//retorna respuesta
+	result = &BulkResponse{}
+	err = decodeResponse(http.MethodPost, resource, body, result)
+	return result, err
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestBulkEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := NewBulkEncoder(&buf, "mailindex")
+
+	operations := []BulkOperation{
+		{Action: BulkActionIndex, ID: "1", Doc: map[string]string{"Subject": "hola"}},
+		{Action: BulkActionCreate, Index: "otro", Doc: json.RawMessage(`{"Subject":"crudo"}`)},
+		{Action: BulkActionUpdate, ID: "1", Doc: map[string]string{"Subject": "corregido"}},
+		{Action: BulkActionDelete, ID: "2", Doc: map[string]string{"ignorado": "si"}},
+	}
+	for _, op := range operations {
+		if err := encoder.Encode(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//update envia el documento tal cual, ZincSearch no acepta {"doc": ...}
+	want := `{"index":{"_index":"mailindex","_id":"1"}}
+{"Subject":"hola"}
+{"create":{"_index":"otro"}}
+{"Subject":"crudo"}
+{"update":{"_index":"mailindex","_id":"1"}}
+{"Subject":"corregido"}
+{"delete":{"_index":"mailindex","_id":"2"}}
+`
+	if buf.String() != want {
+		t.Errorf("NDJSON =\n%s\nse espera\n%s", buf.String(), want)
+	}
+}
+
+func TestBulkEncoderErrors(t *testing.T) {
+	encoder := NewBulkEncoder(&bytes.Buffer{}, "mailindex")
+	for _, op := range []BulkOperation{
+		{Action: BulkActionUpdate, Doc: map[string]string{}},
+		{Action: BulkActionDelete},
+		{Action: "upsert", ID: "1"},
+	} {
+		if err := encoder.Encode(op); err == nil {
+			t.Errorf("Encode(%+v) no retorna error", op)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// configuracion de compresion del cuerpo de las peticiones
+type compression struct {
+	enabled bool
+	level   int
+	minSize int
+}
+
+// indica que el cuerpo alcanzo el tamaño minimo para comprimirse
+var errMinSizeReached = errors.New("zincsearch: tamaño minimo de compresion alcanzado")
+
+// comprime con gzip el cuerpo de una peticion con cuerpo io.Reader; los cuerpos de
+// tamaño conocido menor a minSize se envian sin comprimir
+func (s *ZincSearch) comprimePeticion(req *http.Request) {
+	if !s.compression.enabled || req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+	if req.ContentLength > 0 && req.ContentLength < int64(s.compression.minSize) {
+		return
+	}
+
+	req.Header.Set("Content-Encoding", "gzip")
+	req.ContentLength = -1
+
+	//cuerpo reutilizable: se vuelve a comprimir en cada reintento
+	if getBody := req.GetBody; getBody != nil {
+		setStreamBody(req, gzipWrite(func(w io.Writer) error {
+			body, err := getBody()
+			if err != nil {
+				return err
+			}
+			defer body.Close()
+			_, err = io.Copy(w, body)
+			return err
+		}, s.compression.level))
+		return
+	}
+
+	//cuerpo de una sola lectura, no podra reintentarse
+	body := req.Body
+	req.Body, _ = streamBody(gzipWrite(func(w io.Writer) error {
+		defer body.Close()
+		_, err := io.Copy(w, body)
+		return err
+	}, s.compression.level))()
+}
+
+// asigna cuerpo a una peticion generado por write; si la compresion esta habilitada
+// se genera primero una muestra de minSize bytes: si el contenido completo cabe en
+// ella se envia sin comprimir, de lo contrario se comprime mientras se envia
+func (s *ZincSearch) asignaCuerpoStream(req *http.Request, write func(w io.Writer) error) error {
+	if !s.compression.enabled {
+		setStreamBody(req, write)
+		return nil
+	}
+
+	if s.compression.minSize > 0 {
+		sample := &limitedBuffer{limit: s.compression.minSize}
+		err := write(sample)
+		if err == nil {
+			content := sample.buf.Bytes()
+			req.ContentLength = int64(len(content))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(content)), nil
+			}
+			req.Body, _ = req.GetBody()
+			return nil
+		}
+		if !errors.Is(err, errMinSizeReached) {
+			return err
+		}
+	}
+
+	req.Header.Set("Content-Encoding", "gzip")
+	setStreamBody(req, gzipWrite(write, s.compression.level))
+	return nil
+}
+
+// asigna cuerpo generado por write, de longitud desconocida (Transfer-Encoding: chunked)
+func setStreamBody(req *http.Request, write func(w io.Writer) error) {
+	req.ContentLength = -1
+	req.GetBody = streamBody(write)
+	req.Body, _ = req.GetBody()
+}
+
+// envuelve write para comprimir con gzip lo que escribe
+func gzipWrite(write func(w io.Writer) error, level int) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		gz, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			return err
+		}
+		if err = write(gz); err != nil {
+			return err
+		}
+		return gz.Close()
+	}
+}
+
+// buffer que falla al alcanzar el limite indicado
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.buf.Len()+len(p) >= b.limit {
+		return 0, errMinSizeReached
+	}
+	return b.buf.Write(p)
+}
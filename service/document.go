@@ -1,61 +1,123 @@
 package service
 
 import (
-	"log"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
 	"net/http"
-	"strings"
-	"time"
-
-	"zincsearch.com/mailindex/api/helpers"
+	"net/url"
 )
 
-// guarda indice
-func (s *ZincSearch) CreateDocumentBulk(jsonBody string) (result string, httpError helpers.ErrorResponse) {
+// guarda documentos en bloque (_bulkv2), body contiene {"index": "...", "records": [...]}.
+// Solo se reintenta si body puede volver a leerse (strings.Reader, bytes.Reader, bytes.Buffer)
+func (s *ZincSearch) CreateDocumentBulk(ctx context.Context, body io.Reader) (result *BulkResponse, err error) {
 	const resource string = "/api/_bulkv2"
 
-	h := http.Client{Timeout: 20 * time.Second}
+	//ejecuta peticion
+	response, err := s.ejecutaPeticion(ctx, http.MethodPost, resource, "", body)
+	if err != nil {
+		return result, err
+	}
 
-	//obtiene string del URL
-	url := helpers.GetUrl(s.https, s.host, s.port, resource, "")
+	//retorna respuesta
+	result = &BulkResponse{}
+	err = decodeResponse(http.MethodPost, resource, response, result)
+	return result, err
+}
 
-	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(jsonBody))
+// guarda documentos en bloque (_bulkv2) generando el cuerpo mientras se envia,
+// normalmente con NewBulkV2Encoder. En cada reintento write se invoca de nuevo
+func (s *ZincSearch) CreateDocumentBulkStream(ctx context.Context, write func(w io.Writer) error) (result *BulkResponse, err error) {
+	const resource string = "/api/_bulkv2"
 
+	//ejecuta peticion
+	response, err := s.ejecutaPeticionStream(ctx, http.MethodPost, resource, "", write)
 	if err != nil {
-		return result, helpers.GetErrorResponse(-1, err.Error())
+		return result, err
 	}
 
-	s.debugReq(req)
+	//retorna respuesta
+	result = &BulkResponse{}
+	err = decodeResponse(http.MethodPost, resource, response, result)
+	return result, err
+}
 
-	//agrega credenciales para autenticacion
-	helpers.AddBasicAuth(req, s.usuario, s.password)
-	//ejecuta peticion
-	response, err := h.Do(req)
+// Respuesta de operaciones sobre un documento
+type DocumentResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message,omitempty"`
+	Index   string `json:"index,omitempty"`
+}
+
+// Guarda documento con id generado por el servidor
+func (s *ZincSearch) IndexDocument(ctx context.Context, indexName string, doc interface{}) (result *DocumentResponse, err error) {
+	resource := "/api/" + indexName + "/_doc"
+	return s.guardaDocumento(ctx, http.MethodPost, resource, doc)
+}
+
+// Guarda documento con el id indicado, si existe se reemplaza
+func (s *ZincSearch) PutDocument(ctx context.Context, indexName string, id string, doc interface{}) (result *DocumentResponse, err error) {
+	resource := "/api/" + indexName + "/_doc/" + url.PathEscape(id)
+	return s.guardaDocumento(ctx, http.MethodPut, resource, doc)
+}
+
+// Actualiza documento existente
+func (s *ZincSearch) UpdateDocument(ctx context.Context, indexName string, id string, doc interface{}) (result *DocumentResponse, err error) {
+	resource := "/api/" + indexName + "/_update/" + url.PathEscape(id)
+	result, err = s.guardaDocumento(ctx, http.MethodPost, resource, doc)
 
+	//solo al actualizar un 404 significa que el documento no existe
+	return result, documentNotFound(err)
+}
+
+// Obtiene documento por id, el contenido se decodifica con Hit.Decode
+func (s *ZincSearch) GetDocument(ctx context.Context, indexName string, id string) (result *Hit, err error) {
+	resource := "/api/" + indexName + "/_doc/" + url.PathEscape(id)
+
+	//ejecuta peticion
+	body, err := s.ejecutaPeticion(ctx, http.MethodGet, resource, "", nil)
 	if err != nil {
-		return result, helpers.GetErrorResponse(-1, err.Error())
+		return result, documentNotFound(err)
 	}
 
-	s.debugRes(response)
+	//retorna respuesta
+	result = &Hit{}
+	err = decodeResponse(http.MethodGet, resource, body, result)
+	return result, err
+}
 
-	if response.Body != nil {
-		defer response.Body.Close()
+// Elimina documento por id
+func (s *ZincSearch) DeleteDocument(ctx context.Context, indexName string, id string) (result *DocumentResponse, err error) {
+	resource := "/api/" + indexName + "/_doc/" + url.PathEscape(id)
+
+	//ejecuta peticion
+	body, err := s.ejecutaPeticion(ctx, http.MethodDelete, resource, "", nil)
+	if err != nil {
+		return result, documentNotFound(err)
 	}
 
-	//obtiene resultado como string
-	if response.StatusCode == 200 {
-		result, err = helpers.GetResponseString(response)
-		if err != nil {
-			return result, helpers.GetErrorResponse(-1, err.Error())
-		}
-	} else {
-		httpError, err := helpers.GetError(response)
-		if err != nil {
-			log.Fatal(err)
-		}
-		return result, httpError
+	//retorna respuesta
+	result = &DocumentResponse{}
+	err = decodeResponse(http.MethodDelete, resource, body, result)
+	return result, err
+}
+
+// envia documento serializado como JSON
+func (s *ZincSearch) guardaDocumento(ctx context.Context, method string, resource string, doc interface{}) (result *DocumentResponse, err error) {
+	jsonBody, err := json.Marshal(doc)
+	if err != nil {
+		return result, err
+	}
 
+	//ejecuta peticion
+	body, err := s.ejecutaPeticion(ctx, method, resource, "", bytes.NewReader(jsonBody))
+	if err != nil {
+		return result, err
 	}
 
 	//retorna respuesta
-	return result, httpError
+	result = &DocumentResponse{}
+	err = decodeResponse(method, resource, body, result)
+	return result, err
 }
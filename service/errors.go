@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"zincsearch.com/mailindex/api/helpers"
+)
+
+// Errores conocidos del API de ZincSearch, utilizables con errors.Is
+var (
+	ErrBadRequest         = errors.New("zincsearch: peticion invalida")
+	ErrUnauthorized       = errors.New("zincsearch: credenciales invalidas")
+	ErrForbidden          = errors.New("zincsearch: acceso denegado")
+	ErrNotFound           = errors.New("zincsearch: recurso no encontrado")
+	ErrIndexNotFound      = errors.New("zincsearch: indice no existe")
+	ErrDocumentNotFound   = errors.New("zincsearch: documento no existe")
+	ErrTooManyRequests    = errors.New("zincsearch: demasiadas peticiones")
+	ErrServerError        = errors.New("zincsearch: error del servidor")
+	ErrServiceUnavailable = errors.New("zincsearch: servicio no disponible")
+)
+
+// longitud maxima del mensaje de error tomado del cuerpo de la respuesta
+const maxErrorMessage int = 512
+
+// Error retornado cuando ZincSearch responde con un codigo HTTP distinto de 2xx
+type APIError struct {
+	StatusCode int
+	//mensaje enviado por el servidor, o el cuerpo como texto si no es JSON
+	Message  string
+	Method   string
+	Endpoint string
+	//cuerpo original de la respuesta
+	Body []byte
+
+	//error conocido al que corresponde la respuesta
+	kind error
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("zincsearch: %s %s: %d %s", e.Method, e.Endpoint, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("zincsearch: %s %s: %d %s", e.Method, e.Endpoint, e.StatusCode, e.Message)
+}
+
+// permite comparar con errors.Is contra los errores conocidos
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// crea error a partir de una respuesta no exitosa
+func newAPIError(method string, endpoint string, statusCode int, body []byte) *APIError {
+	httpError := helpers.GetErrorFromBody(statusCode, body)
+
+	//un cuerpo HTML completo no es util como mensaje, queda disponible en Body
+	message := httpError.Error
+	if len(message) > maxErrorMessage {
+		message = message[:maxErrorMessage] + "..."
+	}
+
+	e := &APIError{
+		StatusCode: statusCode,
+		Message:    message,
+		Method:     method,
+		Endpoint:   endpoint,
+		Body:       body,
+	}
+	e.kind = errorKind(statusCode, e.Message)
+	return e
+}
+
+func errorKind(statusCode int, message string) error {
+	//ZincSearch responde 400 o 404 cuando el indice no existe, ej. "index mailindex does not exists"
+	msg := strings.ToLower(message)
+	if strings.Contains(msg, "index") && strings.Contains(msg, "does not exist") {
+		return ErrIndexNotFound
+	}
+
+	switch {
+	case statusCode == http.StatusBadRequest:
+		return ErrBadRequest
+	case statusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case statusCode == http.StatusForbidden:
+		return ErrForbidden
+	case statusCode == http.StatusNotFound:
+		return ErrNotFound
+	case statusCode == http.StatusTooManyRequests:
+		return ErrTooManyRequests
+	case statusCode == http.StatusServiceUnavailable:
+		return ErrServiceUnavailable
+	case statusCode >= 500:
+		return ErrServerError
+	}
+	return nil
+}
+
+// en endpoints de indice un 404 significa que el indice no existe
+func indexNotFound(err error) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.kind == ErrNotFound {
+		apiErr.kind = ErrIndexNotFound
+	}
+	return err
+}
+
+// en endpoints de documento un 404 significa que el documento no existe
+func documentNotFound(err error) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.kind == ErrNotFound {
+		apiErr.kind = ErrDocumentNotFound
+	}
+	return err
+}
@@ -0,0 +1,512 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// Consulta del DSL compatible con Elasticsearch
+type Query interface {
+	// estructura que se serializa como JSON en la peticion
+	Source() map[string]interface{}
+}
+
+// Consulta bool: combina consultas con must, should, filter y must_not
+type BoolQuery struct {
+	must               []Query
+	should             []Query
+	filter             []Query
+	mustNot            []Query
+	minimumShouldMatch interface{}
+}
+
+func NewBoolQuery() *BoolQuery {
+	return &BoolQuery{}
+}
+
+func (q *BoolQuery) Must(queries ...Query) *BoolQuery {
+	q.must = append(q.must, queries...)
+	return q
+}
+
+func (q *BoolQuery) Should(queries ...Query) *BoolQuery {
+	q.should = append(q.should, queries...)
+	return q
+}
+
+func (q *BoolQuery) Filter(queries ...Query) *BoolQuery {
+	q.filter = append(q.filter, queries...)
+	return q
+}
+
+func (q *BoolQuery) MustNot(queries ...Query) *BoolQuery {
+	q.mustNot = append(q.mustNot, queries...)
+	return q
+}
+
+// cantidad (int) o porcentaje (string, ej. "75%") de clausulas should requeridas
+func (q *BoolQuery) MinimumShouldMatch(value interface{}) *BoolQuery {
+	q.minimumShouldMatch = value
+	return q
+}
+
+func (q *BoolQuery) Source() map[string]interface{} {
+	boolQuery := map[string]interface{}{}
+	addClauses(boolQuery, "must", q.must)
+	addClauses(boolQuery, "should", q.should)
+	addClauses(boolQuery, "filter", q.filter)
+	addClauses(boolQuery, "must_not", q.mustNot)
+	if q.minimumShouldMatch != nil {
+		boolQuery["minimum_should_match"] = q.minimumShouldMatch
+	}
+	return map[string]interface{}{"bool": boolQuery}
+}
+
+func addClauses(boolQuery map[string]interface{}, name string, queries []Query) {
+	if len(queries) == 0 {
+		return
+	}
+	clauses := make([]interface{}, 0, len(queries))
+	for _, q := range queries {
+		clauses = append(clauses, q.Source())
+	}
+	boolQuery[name] = clauses
+}
+
+// Consulta match_all, retorna todos los documentos
+type MatchAllQuery struct{}
+
+func NewMatchAllQuery() MatchAllQuery {
+	return MatchAllQuery{}
+}
+
+func (q MatchAllQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"match_all": map[string]interface{}{}}
+}
+
+// Consulta term, valor exacto de un campo
+type TermQuery struct {
+	field string
+	value interface{}
+}
+
+func NewTermQuery(field string, value interface{}) TermQuery {
+	return TermQuery{field: field, value: value}
+}
+
+func (q TermQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"term": map[string]interface{}{q.field: q.value}}
+}
+
+// Consulta terms, cualquiera de los valores exactos indicados
+type TermsQuery struct {
+	field  string
+	values []interface{}
+}
+
+func NewTermsQuery(field string, values ...interface{}) TermsQuery {
+	return TermsQuery{field: field, values: values}
+}
+
+func (q TermsQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"terms": map[string]interface{}{q.field: q.values}}
+}
+
+// Consulta range sobre campos numericos o de fecha
+type RangeQuery struct {
+	field  string
+	params map[string]interface{}
+}
+
+func NewRangeQuery(field string) *RangeQuery {
+	return &RangeQuery{field: field, params: map[string]interface{}{}}
+}
+
+func (q *RangeQuery) Gt(value interface{}) *RangeQuery {
+	q.params["gt"] = value
+	return q
+}
+
+func (q *RangeQuery) Gte(value interface{}) *RangeQuery {
+	q.params["gte"] = value
+	return q
+}
+
+func (q *RangeQuery) Lt(value interface{}) *RangeQuery {
+	q.params["lt"] = value
+	return q
+}
+
+func (q *RangeQuery) Lte(value interface{}) *RangeQuery {
+	q.params["lte"] = value
+	return q
+}
+
+// formato de las fechas indicadas en los limites
+func (q *RangeQuery) Format(format string) *RangeQuery {
+	q.params["format"] = format
+	return q
+}
+
+func (q *RangeQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"range": map[string]interface{}{q.field: q.params}}
+}
+
+// Consulta match, texto analizado sobre un campo
+type MatchQuery struct {
+	field    string
+	text     string
+	operator string
+}
+
+func NewMatchQuery(field string, text string) *MatchQuery {
+	return &MatchQuery{field: field, text: text}
+}
+
+// "and" requiere todos los terminos, "or" (por defecto) cualquiera
+func (q *MatchQuery) Operator(operator string) *MatchQuery {
+	q.operator = operator
+	return q
+}
+
+func (q *MatchQuery) Source() map[string]interface{} {
+	params := map[string]interface{}{"query": q.text}
+	if q.operator != "" {
+		params["operator"] = q.operator
+	}
+	return map[string]interface{}{"match": map[string]interface{}{q.field: params}}
+}
+
+// Consulta match_phrase, frase exacta sobre un campo
+type MatchPhraseQuery struct {
+	field string
+	text  string
+}
+
+func NewMatchPhraseQuery(field string, text string) MatchPhraseQuery {
+	return MatchPhraseQuery{field: field, text: text}
+}
+
+func (q MatchPhraseQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"match_phrase": map[string]interface{}{q.field: q.text}}
+}
+
+// Consulta multi_match, texto analizado sobre varios campos
+type MultiMatchQuery struct {
+	text      string
+	fields    []string
+	matchType string
+	operator  string
+}
+
+func NewMultiMatchQuery(text string, fields ...string) *MultiMatchQuery {
+	return &MultiMatchQuery{text: text, fields: fields}
+}
+
+// tipo de multi_match: best_fields, most_fields, cross_fields, phrase, phrase_prefix
+func (q *MultiMatchQuery) Type(matchType string) *MultiMatchQuery {
+	q.matchType = matchType
+	return q
+}
+
+func (q *MultiMatchQuery) Operator(operator string) *MultiMatchQuery {
+	q.operator = operator
+	return q
+}
+
+func (q *MultiMatchQuery) Source() map[string]interface{} {
+	params := map[string]interface{}{"query": q.text}
+	if len(q.fields) > 0 {
+		params["fields"] = q.fields
+	}
+	if q.matchType != "" {
+		params["type"] = q.matchType
+	}
+	if q.operator != "" {
+		params["operator"] = q.operator
+	}
+	return map[string]interface{}{"multi_match": params}
+}
+
+// Consulta exists, documentos con valor en el campo
+type ExistsQuery struct {
+	field string
+}
+
+func NewExistsQuery(field string) ExistsQuery {
+	return ExistsQuery{field: field}
+}
+
+func (q ExistsQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"exists": map[string]interface{}{"field": q.field}}
+}
+
+// Consulta query_string con sintaxis de Lucene
+type QueryStringQuery struct {
+	query string
+}
+
+func NewQueryStringQuery(query string) QueryStringQuery {
+	return QueryStringQuery{query: query}
+}
+
+func (q QueryStringQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"query_string": map[string]interface{}{"query": q.query}}
+}
+
+// Agregacion del DSL compatible con Elasticsearch
+type Aggregation interface {
+	Source() map[string]interface{}
+}
+
+// Agregacion terms, un bucket por cada valor del campo
+type TermsAggregation struct {
+	field   string
+	size    int
+	subAggs map[string]Aggregation
+}
+
+func NewTermsAggregation(field string) *TermsAggregation {
+	return &TermsAggregation{field: field}
+}
+
+// cantidad maxima de buckets
+func (a *TermsAggregation) Size(size int) *TermsAggregation {
+	a.size = size
+	return a
+}
+
+func (a *TermsAggregation) SubAggregation(name string, sub Aggregation) *TermsAggregation {
+	if a.subAggs == nil {
+		a.subAggs = map[string]Aggregation{}
+	}
+	a.subAggs[name] = sub
+	return a
+}
+
+func (a *TermsAggregation) Source() map[string]interface{} {
+	params := map[string]interface{}{"field": a.field}
+	if a.size > 0 {
+		params["size"] = a.size
+	}
+	return aggregationSource("terms", params, a.subAggs)
+}
+
+// Agregacion date_histogram, un bucket por intervalo de tiempo
+type DateHistogramAggregation struct {
+	field    string
+	interval string
+	format   string
+	subAggs  map[string]Aggregation
+}
+
+// interval es el intervalo calendario: minute, hour, day, week, month, quarter, year
+func NewDateHistogramAggregation(field string, interval string) *DateHistogramAggregation {
+	return &DateHistogramAggregation{field: field, interval: interval}
+}
+
+func (a *DateHistogramAggregation) Format(format string) *DateHistogramAggregation {
+	a.format = format
+	return a
+}
+
+func (a *DateHistogramAggregation) SubAggregation(name string, sub Aggregation) *DateHistogramAggregation {
+	if a.subAggs == nil {
+		a.subAggs = map[string]Aggregation{}
+	}
+	a.subAggs[name] = sub
+	return a
+}
+
+func (a *DateHistogramAggregation) Source() map[string]interface{} {
+	params := map[string]interface{}{"field": a.field, "calendar_interval": a.interval}
+	if a.format != "" {
+		params["format"] = a.format
+	}
+	return aggregationSource("date_histogram", params, a.subAggs)
+}
+
+// Agregacion de metrica sobre un campo: avg, sum, min, max, cardinality
+type MetricAggregation struct {
+	metric string
+	field  string
+}
+
+func NewAvgAggregation(field string) MetricAggregation {
+	return MetricAggregation{metric: "avg", field: field}
+}
+
+func NewSumAggregation(field string) MetricAggregation {
+	return MetricAggregation{metric: "sum", field: field}
+}
+
+func NewMinAggregation(field string) MetricAggregation {
+	return MetricAggregation{metric: "min", field: field}
+}
+
+func NewMaxAggregation(field string) MetricAggregation {
+	return MetricAggregation{metric: "max", field: field}
+}
+
+func NewCardinalityAggregation(field string) MetricAggregation {
+	return MetricAggregation{metric: "cardinality", field: field}
+}
+
+func (a MetricAggregation) Source() map[string]interface{} {
+	return aggregationSource(a.metric, map[string]interface{}{"field": a.field}, nil)
+}
+
+func aggregationSource(aggType string, params map[string]interface{}, subAggs map[string]Aggregation) map[string]interface{} {
+	source := map[string]interface{}{aggType: params}
+	if len(subAggs) > 0 {
+		source["aggs"] = aggregationsSource(subAggs)
+	}
+	return source
+}
+
+func aggregationsSource(aggs map[string]Aggregation) map[string]interface{} {
+	source := make(map[string]interface{}, len(aggs))
+	for name, agg := range aggs {
+		source[name] = agg.Source()
+	}
+	return source
+}
+
+// Criterio de ordenamiento
+type ESSort struct {
+	Field string
+	Desc  bool
+}
+
+// Cantidad de hits para ESSearchRequest.Size
+func Size(n int) *int {
+	return &n
+}
+
+// Peticion de busqueda al endpoint compatible con Elasticsearch (/es/{indice}/_search)
+type ESSearchRequest struct {
+	Query Query
+	From  int
+	//cantidad de hits, nil utiliza el valor por defecto del servidor. Size(0)
+	//retorna solo agregaciones, sin hits
+	Size *int
+	Sort []ESSort
+	//campos a incluir en _source, vacio retorna todos
+	Source       []string
+	Aggregations map[string]Aggregation
+}
+
+func (r ESSearchRequest) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{}
+
+	if r.Query != nil {
+		body["query"] = r.Query.Source()
+	}
+	if r.From > 0 {
+		body["from"] = r.From
+	}
+	if r.Size != nil {
+		body["size"] = *r.Size
+	}
+	if len(r.Sort) > 0 {
+		sort := make([]interface{}, 0, len(r.Sort))
+		for _, s := range r.Sort {
+			order := "asc"
+			if s.Desc {
+				order = "desc"
+			}
+			sort = append(sort, map[string]interface{}{s.Field: map[string]string{"order": order}})
+		}
+		body["sort"] = sort
+	}
+	if len(r.Source) > 0 {
+		body["_source"] = r.Source
+	}
+	if len(r.Aggregations) > 0 {
+		body["aggs"] = aggregationsSource(r.Aggregations)
+	}
+
+	return json.Marshal(body)
+}
+
+// Resultado de busqueda del endpoint compatible con Elasticsearch
+type ESSearchResult struct {
+	Took         int64                        `json:"took"`
+	TimedOut     bool                         `json:"timed_out"`
+	Hits         SearchHits                   `json:"hits"`
+	Aggregations map[string]AggregationResult `json:"aggregations,omitempty"`
+}
+
+// Resultado de una agregacion: Buckets para agregaciones de grupo,
+// Value para agregaciones de metrica
+type AggregationResult struct {
+	Value   *float64 `json:"value,omitempty"`
+	Buckets []Bucket `json:"buckets,omitempty"`
+}
+
+type Bucket struct {
+	Key         interface{} `json:"key"`
+	KeyAsString string      `json:"key_as_string,omitempty"`
+	DocCount    int64       `json:"doc_count"`
+	//resultado de sub agregaciones, por nombre
+	Aggregations map[string]AggregationResult `json:"-"`
+}
+
+// las sub agregaciones vienen como propiedades adicionales del bucket
+func (b *Bucket) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	for name, raw := range fields {
+		var err error
+		switch name {
+		case "key":
+			err = json.Unmarshal(raw, &b.Key)
+		case "key_as_string":
+			err = json.Unmarshal(raw, &b.KeyAsString)
+		case "doc_count":
+			err = json.Unmarshal(raw, &b.DocCount)
+		default:
+			//solo objetos pueden ser sub agregaciones
+			if len(raw) == 0 || raw[0] != '{' {
+				continue
+			}
+			var sub AggregationResult
+			if err = json.Unmarshal(raw, &sub); err == nil {
+				if b.Aggregations == nil {
+					b.Aggregations = map[string]AggregationResult{}
+				}
+				b.Aggregations[name] = sub
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ejecuta busqueda con el DSL compatible con Elasticsearch
+func (s *ZincSearch) ESSearch(ctx context.Context, indexName string, request ESSearchRequest) (result *ESSearchResult, err error) {
+	resource := "/es/" + indexName + "/_search"
+
+	jsonBody, err := json.Marshal(request)
+	if err != nil {
+		return result, err
+	}
+
+	//ejecuta peticion
+	body, err := s.ejecutaPeticion(ctx, http.MethodPost, resource, "", bytes.NewReader(jsonBody))
+	if err != nil {
+		return result, indexNotFound(err)
+	}
+
+	//retorna respuesta
+	result = &ESSearchResult{}
+	err = decodeResponse(http.MethodPost, resource, body, result)
+	return result, err
+}
@@ -1,10 +1,10 @@
 package service
 
 import (
-	"log"
+	"context"
+	"errors"
 	"net/http"
 	"strings"
-	"time"
 
 	"zincsearch.com/mailindex/api/helpers"
 )
@@ -17,251 +17,91 @@ type IndexListRequest struct {
 	Name      string
 }
 
-func (s *ZincSearch) GetIndexList(request IndexListRequest) (result string, httpError helpers.ErrorResponse) {
+// obtiene listado paginado de indices
+func (s *ZincSearch) GetIndexList(ctx context.Context, request IndexListRequest) (result *IndexList, err error) {
 	const resource string = "/api/index"
 
-	h := http.Client{Timeout: 20 * time.Second}
-
-	//obtiene string del URL
 	urlQuery := helpers.GetUrlQueryFromStruct(request)
-	url := helpers.GetUrl(s.https, s.host, s.port, resource, urlQuery)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return result, helpers.GetErrorResponse(-1, err.Error())
-	} else {
-		req.Header.Add("Content-Type", "application/json")
-	}
-
-	s.debugReq(req)
 
-	//agrega credenciales para autenticacion
-	helpers.AddBasicAuth(req, s.usuario, s.password)
 	//ejecuta peticion
-	response, err := h.Do(req)
-
+	body, err := s.ejecutaPeticion(ctx, http.MethodGet, resource, urlQuery, nil)
 	if err != nil {
-		return result, helpers.GetErrorResponse(-1, err.Error())
-	}
-	s.debugRes(response)
-
-	if response.Body != nil {
-		defer response.Body.Close()
-	}
-
-	//obtiene resultado como string
-	if response.StatusCode == 200 {
-		result, err = helpers.GetResponseString(response)
-		if err != nil {
-			return result, helpers.GetErrorResponse(-1, err.Error())
-		}
-	} else {
-		httpError, err := helpers.GetError(response)
-		if err != nil {
-			log.Fatal(err)
-		}
-		return result, httpError
-
+		return result, err
 	}
 
 	//retorna respuesta
-	return result, httpError
-
+	result = &IndexList{}
+	err = decodeResponse(http.MethodGet, resource, body, result)
+	return result, err
 }
 
-func (s *ZincSearch) ExistsIndex(indexName string) (result bool, httpError helpers.ErrorResponse) {
+// verifica existencia de indice, un indice inexistente no se considera error
+func (s *ZincSearch) ExistsIndex(ctx context.Context, indexName string) (result bool, err error) {
 	var sb strings.Builder
 	sb.WriteString("/api/index/")
 	sb.WriteString(indexName)
 
-	h := http.Client{Timeout: 20 * time.Second}
-
-	//obtiene string del URL
-	url := helpers.GetUrl(s.https, s.host, s.port, sb.String(), "")
-
-	req, err := http.NewRequest(http.MethodHead, url, nil)
-
-	if err != nil {
-		return result, helpers.GetErrorResponse(-1, err.Error())
-	}
-	s.debugReq(req)
-
-	//agrega credenciales para autenticacion
-	helpers.AddBasicAuth(req, s.usuario, s.password)
 	//ejecuta peticion
-	response, err := h.Do(req)
-
-	if err != nil {
-		return result, helpers.GetErrorResponse(-1, err.Error())
+	_, err = s.ejecutaPeticion(ctx, http.MethodHead, sb.String(), "", nil)
+	if errors.Is(indexNotFound(err), ErrIndexNotFound) {
+		return false, nil
 	}
-
-	s.debugRes(response)
-
-	if response.Body != nil {
-		defer response.Body.Close()
+	if err != nil {
+		return false, err
 	}
 
-	//obtiene resultado como string
-	result = response.StatusCode == 200
-
 	//retorna respuesta
-	return result, httpError
-
+	return true, nil
 }
 
-func (s *ZincSearch) GetIndex(indexName string) (result string, httpError helpers.ErrorResponse) {
+// obtiene metadata de indice
+func (s *ZincSearch) GetIndex(ctx context.Context, indexName string) (result *Index, err error) {
 	var sb strings.Builder
 	sb.WriteString("/api/index/")
 	sb.WriteString(indexName)
 
-	h := http.Client{Timeout: 20 * time.Second}
-
-	//obtiene string del URL
-	url := helpers.GetUrl(s.https, s.host, s.port, sb.String(), "")
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-
-	if err != nil {
-		return result, helpers.GetErrorResponse(-1, err.Error())
-	}
-
-	s.debugReq(req)
-
-	//agrega credenciales para autenticacion
-	helpers.AddBasicAuth(req, s.usuario, s.password)
 	//ejecuta peticion
-	response, err := h.Do(req)
-
+	body, err := s.ejecutaPeticion(ctx, http.MethodGet, sb.String(), "", nil)
 	if err != nil {
-		return result, helpers.GetErrorResponse(-1, err.Error())
-	}
-
-	s.debugRes(response)
-
-	if response.Body != nil {
-		defer response.Body.Close()
-	}
-
-	//obtiene resultado como string
-	if response.StatusCode == 200 {
-		result, err = helpers.GetResponseString(response)
-		if err != nil {
-			return result, helpers.GetErrorResponse(-1, err.Error())
-		}
-	} else {
-		httpError, err := helpers.GetError(response)
-		if err != nil {
-			log.Fatal(err)
-		}
-		return result, httpError
-
+		return result, indexNotFound(err)
 	}
 
 	//retorna respuesta
-	return result, httpError
-
+	result = &Index{}
+	err = decodeResponse(http.MethodGet, sb.String(), body, result)
+	return result, err
 }
 
 // guarda indice
-func (s *ZincSearch) SaveIndex(indexName string, jsonBody string) (result string, httpError helpers.ErrorResponse) {
+func (s *ZincSearch) SaveIndex(ctx context.Context, indexName string, jsonBody string) (result *IndexResponse, err error) {
 	const resource string = "/api/index"
 
-	h := http.Client{Timeout: 20 * time.Second}
-
-	//obtiene string del URL
-	url := helpers.GetUrl(s.https, s.host, s.port, resource, "")
-
-	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(jsonBody))
-
-	if err != nil {
-		return result, helpers.GetErrorResponse(-1, err.Error())
-	}
-
-	s.debugReq(req)
-
-	//agrega credenciales para autenticacion
-	helpers.AddBasicAuth(req, s.usuario, s.password)
 	//ejecuta peticion
-	response, err := h.Do(req)
-
+	body, err := s.ejecutaPeticion(ctx, http.MethodPost, resource, "", strings.NewReader(jsonBody))
 	if err != nil {
-		return result, helpers.GetErrorResponse(-1, err.Error())
-	}
-
-	s.debugRes(response)
-
-	if response.Body != nil {
-		defer response.Body.Close()
-	}
-
-	//obtiene resultado como string
-	if response.StatusCode == 200 {
-		result, err = helpers.GetResponseString(response)
-		if err != nil {
-			return result, helpers.GetErrorResponse(-1, err.Error())
-		}
-	} else {
-		httpError, err := helpers.GetError(response)
-		if err != nil {
-			log.Fatal(err)
-		}
-		return result, httpError
-
+		return result, err
 	}
 
 	//retorna respuesta
-	return result, httpError
+	result = &IndexResponse{}
+	err = decodeResponse(http.MethodPost, resource, body, result)
+	return result, err
 }
 
 // Elimina indice
-func (s *ZincSearch) DeleteIndex(indexName string) (result string, httpError helpers.ErrorResponse) {
+func (s *ZincSearch) DeleteIndex(ctx context.Context, indexName string) (result *IndexResponse, err error) {
 	var sb strings.Builder
 	sb.WriteString("/api/index/")
 	sb.WriteString(indexName)
 
-	h := http.Client{Timeout: 20 * time.Second}
-
-	//obtiene string del URL
-	url := helpers.GetUrl(s.https, s.host, s.port, sb.String(), "")
-
-	req, err := http.NewRequest(http.MethodDelete, url, nil)
-
-	if err != nil {
-		return result, helpers.GetErrorResponse(-1, err.Error())
-	}
-
-	s.debugReq(req)
-
-	//agrega credenciales para autenticacion
-	helpers.AddBasicAuth(req, s.usuario, s.password)
 	//ejecuta peticion
-	response, err := h.Do(req)
-
+	body, err := s.ejecutaPeticion(ctx, http.MethodDelete, sb.String(), "", nil)
 	if err != nil {
-		return result, helpers.GetErrorResponse(-1, err.Error())
-	}
-
-	s.debugRes(response)
-
-	if response.Body != nil {
-		defer response.Body.Close()
-	}
-
-	//obtiene resultado como string
-	if response.StatusCode == 200 {
-		result, err = helpers.GetResponseString(response)
-		if err != nil {
-			return result, helpers.GetErrorResponse(-1, err.Error())
-		}
-	} else {
-		httpError, err := helpers.GetError(response)
-		if err != nil {
-			log.Fatal(err)
-		}
-		return result, httpError
-
+		return result, indexNotFound(err)
 	}
 
 	//retorna respuesta
-	return result, httpError
+	result = &IndexResponse{}
+	err = decodeResponse(http.MethodDelete, sb.String(), body, result)
+	return result, err
 }
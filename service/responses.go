@@ -0,0 +1,141 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Metadata de indice, respuesta de GET /api/index/{indice}
+type Index struct {
+	Name        string         `json:"name"`
+	StorageType string         `json:"storage_type"`
+	ShardNum    int            `json:"shard_num"`
+	Settings    *IndexSettings `json:"settings,omitempty"`
+	Mappings    *IndexMappings `json:"mappings,omitempty"`
+	Stats       IndexStats     `json:"stats"`
+}
+
+// Cantidad de documentos del indice
+func (i *Index) DocCount() int64 {
+	return i.Stats.DocNum
+}
+
+// Espacio en disco utilizado por el indice, en bytes
+func (i *Index) StorageSize() int64 {
+	return i.Stats.StorageSize
+}
+
+type IndexSettings struct {
+	NumberOfShards   int             `json:"number_of_shards,omitempty"`
+	NumberOfReplicas int             `json:"number_of_replicas,omitempty"`
+	Analysis         json.RawMessage `json:"analysis,omitempty"`
+}
+
+type IndexMappings struct {
+	Properties map[string]IndexProperty `json:"properties"`
+}
+
+// Definicion de un campo del indice
+type IndexProperty struct {
+	Type           string `json:"type"`
+	Index          bool   `json:"index"`
+	Store          bool   `json:"store"`
+	Sortable       bool   `json:"sortable"`
+	Aggregatable   bool   `json:"aggregatable"`
+	Highlightable  bool   `json:"highlightable"`
+	Analyzer       string `json:"analyzer,omitempty"`
+	SearchAnalyzer string `json:"search_analyzer,omitempty"`
+	Format         string `json:"format,omitempty"`
+}
+
+type IndexStats struct {
+	DocNum      int64 `json:"doc_num"`
+	StorageSize int64 `json:"storage_size"`
+	WalSize     int64 `json:"wal_size"`
+	DocTimeMin  int64 `json:"doc_time_min"`
+	DocTimeMax  int64 `json:"doc_time_max"`
+}
+
+// Listado paginado de indices, respuesta de GET /api/index
+type IndexList struct {
+	List []Index `json:"list"`
+	Page Page    `json:"page"`
+}
+
+type Page struct {
+	PageNum  int64 `json:"page_num"`
+	PageSize int64 `json:"page_size"`
+	Total    int64 `json:"total"`
+}
+
+// Respuesta de creacion y eliminacion de indice
+type IndexResponse struct {
+	Message     string `json:"message"`
+	Index       string `json:"index"`
+	StorageType string `json:"storage_type,omitempty"`
+}
+
+// Respuesta de carga bulk. _bulkv2 solo informa la cantidad de registros,
+// _bulk puede incluir ademas el resultado de cada documento en Items
+type BulkResponse struct {
+	Message     string                      `json:"message"`
+	RecordCount int64                       `json:"record_count"`
+	Took        int64                       `json:"took,omitempty"`
+	Errors      bool                        `json:"errors,omitempty"`
+	Items       []map[string]BulkItemResult `json:"items,omitempty"`
+}
+
+// Resultado de un documento dentro de una carga bulk
+type BulkItemResult struct {
+	//accion ejecutada: index, create, update o delete
+	Action string         `json:"-"`
+	Index  string         `json:"_index"`
+	ID     string         `json:"_id"`
+	Status int            `json:"status"`
+	Result string         `json:"result,omitempty"`
+	Error  *BulkItemError `json:"error,omitempty"`
+}
+
+type BulkItemError struct {
+	Type   string `json:"type,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// el error puede venir como texto o como objeto {type, reason}
+func (e *BulkItemError) UnmarshalJSON(data []byte) error {
+	var reason string
+	if err := json.Unmarshal(data, &reason); err == nil {
+		e.Reason = reason
+		return nil
+	}
+	type bulkItemError BulkItemError
+	return json.Unmarshal(data, (*bulkItemError)(e))
+}
+
+func (e *BulkItemError) Error() string {
+	if e.Type == "" {
+		return e.Reason
+	}
+	return e.Type + ": " + e.Reason
+}
+
+// Obtiene documentos rechazados por el servidor
+func (r *BulkResponse) Failures() (failures []BulkItemResult) {
+	for _, item := range r.Items {
+		for action, result := range item {
+			if result.Error != nil || result.Status > 299 {
+				result.Action = action
+				failures = append(failures, result)
+			}
+		}
+	}
+	return failures
+}
+
+// decodifica respuesta JSON de ZincSearch
+func decodeResponse(method string, resource string, body []byte, v interface{}) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("zincsearch: %s %s: respuesta invalida: %w", method, resource, err)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"zincsearch.com/mailindex/api/helpers"
+)
+
+// variables de ambiente para configuracion de reintentos
+const ZincSearchRetryMaxAttempts string = "ZINC_SERVER_RETRY_MAX_ATTEMPTS"
+const ZincSearchRetryInitialBackoff string = "ZINC_SERVER_RETRY_INITIAL_BACKOFF"
+const ZincSearchRetryMaxBackoff string = "ZINC_SERVER_RETRY_MAX_BACKOFF"
+const ZincSearchRetryStatus string = "ZINC_SERVER_RETRY_STATUS"
+
+// Politica de reintentos para peticiones hacia ZincSearch.
+//
+// Se reintentan errores de red y los codigos HTTP de RetryableStatus. La espera
+// entre intentos crece de forma exponencial (InitialBackoff * Multiplier^n, hasta
+// MaxBackoff) con variacion aleatoria de +/- Jitter. Si el servidor envia el
+// header Retry-After se espera lo que este indique, sin exceder MaxBackoff.
+//
+// Solo se reintentan peticiones cuyo cuerpo puede volver a leerse (strings.Reader,
+// bytes.Reader, bytes.Buffer o GetBody definido).
+type RetryPolicy struct {
+	//total de intentos incluyendo el primero, 1 deshabilita reintentos
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	//fraccion (0 a 1) de variacion aleatoria aplicada a cada espera
+	Jitter          float64
+	RetryableStatus []int
+}
+
+// Politica de reintentos por defecto
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:     5,
+		InitialBackoff:  500 * time.Millisecond,
+		MaxBackoff:      30 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.2,
+		RetryableStatus: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	}
+}
+
+// Obtiene politica de reintentos de variables de ambiente,
+// los valores no definidos conservan su valor por defecto
+func RetryPolicyFromEnv() (policy RetryPolicy, err error) {
+	policy = DefaultRetryPolicy()
+
+	if policy.MaxAttempts, err = helpers.GetEnvInt(ZincSearchRetryMaxAttempts, policy.MaxAttempts); err != nil {
+		return policy, err
+	}
+	if policy.InitialBackoff, err = helpers.GetEnvDuration(ZincSearchRetryInitialBackoff, policy.InitialBackoff); err != nil {
+		return policy, err
+	}
+	if policy.MaxBackoff, err = helpers.GetEnvDuration(ZincSearchRetryMaxBackoff, policy.MaxBackoff); err != nil {
+		return policy, err
+	}
+
+	//lista de codigos separados por coma, ej. 429,502,503,504
+	if txt := os.Getenv(ZincSearchRetryStatus); txt != "" {
+		policy.RetryableStatus = nil
+		for _, code := range strings.Split(txt, ",") {
+			status, err := strconv.Atoi(strings.TrimSpace(code))
+			if err != nil {
+				return policy, fmt.Errorf("valor invalido para %s: %q", ZincSearchRetryStatus, txt)
+			}
+			policy.RetryableStatus = append(policy.RetryableStatus, status)
+		}
+	}
+
+	return policy, nil
+}
+
+// Reemplaza la politica de reintentos
+func (s *ZincSearch) SetRetryPolicy(policy RetryPolicy) {
+	s.retry = &policy
+}
+
+// Contadores de peticiones e intentos realizados
+type RetryStats struct {
+	Requests int64 //peticiones ejecutadas
+	Attempts int64 //intentos totales, incluyendo reintentos
+	Retries  int64 //reintentos realizados
+	Failures int64 //peticiones que agotaron los intentos
+}
+
+// Obtiene contadores de intentos desde la inicializacion del servicio
+func (s *ZincSearch) RetryStats() RetryStats {
+	return RetryStats{
+		Requests: s.requests.Load(),
+		Attempts: s.attempts.Load(),
+		Retries:  s.retries.Load(),
+		Failures: s.failures.Load(),
+	}
+}
+
+func (s *ZincSearch) retryPolicy() RetryPolicy {
+	if s.retry == nil {
+		return DefaultRetryPolicy()
+	}
+	return *s.retry
+}
+
+// ejecuta peticion aplicando politica de reintentos
+func (s *ZincSearch) do(req *http.Request) (*http.Response, error) {
+	policy := s.retryPolicy()
+	ctx := req.Context()
+
+	//sin cuerpo reutilizable no es posible repetir la peticion
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	s.requests.Add(1)
+	for attempt := 1; ; attempt++ {
+		s.attempts.Add(1)
+
+		if attempt > 1 {
+			var err error
+			req, err = cloneRequest(req)
+			if err != nil {
+				s.failures.Add(1)
+				return nil, err
+			}
+		}
+
+		response, err := s.httpClient().Do(req)
+
+		//cancelacion del contexto nunca se reintenta
+		if ctx.Err() != nil {
+			if response != nil {
+				response.Body.Close()
+			}
+			s.failures.Add(1)
+			return nil, ctx.Err()
+		}
+
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else if policy.isRetryableStatus(response.StatusCode) {
+			reason = response.Status
+		} else {
+			return response, nil
+		}
+
+		if attempt >= policy.MaxAttempts || !replayable {
+			s.failures.Add(1)
+			log.Printf("ZincSearch %s %s: intento %d/%d fallido (%s), no se reintenta", req.Method, req.URL.Path, attempt, policy.MaxAttempts, reason)
+			return response, err
+		}
+
+		wait := policy.backoff(attempt)
+		if response != nil {
+			if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+				wait = retryAfter
+				//un Retry-After muy largo detendria al worker indefinidamente
+				if policy.MaxBackoff > 0 && wait > policy.MaxBackoff {
+					wait = policy.MaxBackoff
+				}
+			}
+			//descarta cuerpo para reutilizar la conexion
+			io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+		}
+
+		log.Printf("ZincSearch %s %s: intento %d/%d fallido (%s), reintentando en %s", req.Method, req.URL.Path, attempt, policy.MaxAttempts, reason, wait)
+		s.retries.Add(1)
+
+		if err := sleepContext(ctx, wait); err != nil {
+			s.failures.Add(1)
+			return nil, err
+		}
+	}
+}
+
+func (p RetryPolicy) isRetryableStatus(code int) bool {
+	for _, status := range p.RetryableStatus {
+		if status == code {
+			return true
+		}
+	}
+	return false
+}
+
+// calcula espera previa al siguiente intento
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	wait := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && wait > float64(p.MaxBackoff) {
+		wait = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		wait = wait * (1 - p.Jitter + rand.Float64()*2*p.Jitter)
+	}
+	return time.Duration(wait)
+}
+
+// interpreta header Retry-After, en segundos o como fecha HTTP
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// crea copia de la peticion con un nuevo cuerpo para reintentarla
+func cloneRequest(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
+
+func sleepContext(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// politica sin variacion aleatoria y con esperas cortas
+func testRetryPolicy() RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = 3
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 50 * time.Millisecond
+	policy.Jitter = 0
+	return policy
+}
+
+// servidor de prueba que registra el cuerpo recibido en cada intento y
+// responde con el codigo indicado por status
+type retryServer struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (r *retryServer) attempts() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.bodies)
+}
+
+func newRetryServer(t *testing.T, status func(attempt int, w http.ResponseWriter) int) (*ZincSearch, *retryServer) {
+	t.Helper()
+	recorder := &retryServer{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("cuerpo gzip invalido: %v", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			reader = gz
+		}
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			t.Errorf("error al leer cuerpo: %v", err)
+		}
+
+		recorder.mu.Lock()
+		recorder.bodies = append(recorder.bodies, string(body))
+		attempt := len(recorder.bodies)
+		recorder.mu.Unlock()
+
+		code := status(attempt, w)
+		w.WriteHeader(code)
+		if code == http.StatusOK {
+			io.WriteString(w, `{"message":"ok"}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	serverUrl, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &ZincSearch{host: serverUrl.Hostname(), port: serverUrl.Port()}
+	s.SetRetryPolicy(testRetryPolicy())
+	return s, recorder
+}
+
+// 503 en los primeros intentos indicados, luego 200
+func failFirst(n int) func(attempt int, w http.ResponseWriter) int {
+	return func(attempt int, w http.ResponseWriter) int {
+		if attempt <= n {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	}
+}
+
+func TestRetryReplaysBody(t *testing.T) {
+	s, server := newRetryServer(t, failFirst(1))
+
+	const body = `{"name":"mailindex"}`
+	if _, err := s.ejecutaPeticion(context.Background(), http.MethodPost, "/api/index", "", strings.NewReader(body)); err != nil {
+		t.Fatal(err)
+	}
+	if server.attempts() != 2 || server.bodies[0] != body || server.bodies[1] != body {
+		t.Errorf("cuerpos recibidos %q, se espera %q dos veces", server.bodies, body)
+	}
+	if stats := s.RetryStats(); stats.Requests != 1 || stats.Attempts != 2 || stats.Retries != 1 || stats.Failures != 0 {
+		t.Errorf("RetryStats = %+v", stats)
+	}
+}
+
+func TestRetryReplaysGzipStream(t *testing.T) {
+	s, server := newRetryServer(t, failFirst(2))
+	cfg := DefaultTransportConfig()
+	cfg.Gzip = true
+	cfg.GzipMinSize = 16
+	if err := s.SetTransport(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	//el cuerpo se genera de nuevo en cada intento
+	want := strings.Repeat(`{"index":{}}`+"\n"+`{"Subject":"hola"}`+"\n", 100)
+	writes := 0
+	write := func(w io.Writer) error {
+		writes++
+		_, err := io.WriteString(w, want)
+		return err
+	}
+	if _, err := s.ejecutaPeticionStream(context.Background(), http.MethodPost, "/api/_bulk", "", write); err != nil {
+		t.Fatal(err)
+	}
+	if server.attempts() != 3 {
+		t.Fatalf("intentos = %d, se espera 3", server.attempts())
+	}
+	for i, body := range server.bodies {
+		if body != want {
+			t.Errorf("intento %d recibio %d bytes, se esperan %d", i+1, len(body), len(want))
+		}
+	}
+	//muestra para decidir si se comprime, y un cuerpo por intento
+	if writes != 4 {
+		t.Errorf("write invocado %d veces, se esperan 4", writes)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	s, server := newRetryServer(t, failFirst(100))
+
+	_, err := s.ejecutaPeticion(context.Background(), http.MethodGet, "/api/index", "", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("error = %v, se espera APIError 503", err)
+	}
+	if !errors.Is(err, ErrServiceUnavailable) {
+		t.Errorf("error = %v, se espera %v", err, ErrServiceUnavailable)
+	}
+	if server.attempts() != 3 {
+		t.Errorf("intentos = %d, se espera 3", server.attempts())
+	}
+	if stats := s.RetryStats(); stats.Retries != 2 || stats.Failures != 1 {
+		t.Errorf("RetryStats = %+v", stats)
+	}
+}
+
+func TestRetryNotReplayableBody(t *testing.T) {
+	s, server := newRetryServer(t, failFirst(1))
+
+	//un io.Reader sin GetBody no puede volver a leerse
+	body := io.MultiReader(strings.NewReader(`{"a":1}`))
+	_, err := s.ejecutaPeticion(context.Background(), http.MethodPost, "/api/index", "", body)
+	if !errors.Is(err, ErrServiceUnavailable) || server.attempts() != 1 {
+		t.Errorf("error = %v con %d intentos, se espera 503 sin reintentos", err, server.attempts())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		maxBackoff time.Duration
+		min, max   time.Duration
+	}{
+		{"respeta Retry-After", "1", 5 * time.Second, time.Second, 3 * time.Second},
+		{"limitado a MaxBackoff", "3600", 50 * time.Millisecond, 50 * time.Millisecond, 2 * time.Second},
+		{"fecha limitada a MaxBackoff", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), 50 * time.Millisecond, 50 * time.Millisecond, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, server := newRetryServer(t, func(attempt int, w http.ResponseWriter) int {
+				if attempt == 1 {
+					w.Header().Set("Retry-After", tt.retryAfter)
+					return http.StatusTooManyRequests
+				}
+				return http.StatusOK
+			})
+			policy := testRetryPolicy()
+			policy.MaxBackoff = tt.maxBackoff
+			s.SetRetryPolicy(policy)
+
+			start := time.Now()
+			if _, err := s.ejecutaPeticion(context.Background(), http.MethodGet, "/api/index", "", nil); err != nil {
+				t.Fatal(err)
+			}
+			elapsed := time.Since(start)
+			if elapsed < tt.min || elapsed > tt.max {
+				t.Errorf("espera de %s, se espera entre %s y %s", elapsed, tt.min, tt.max)
+			}
+			if server.attempts() != 2 {
+				t.Errorf("intentos = %d, se espera 2", server.attempts())
+			}
+		})
+	}
+}
+
+func TestRetryCancelDuringBackoff(t *testing.T) {
+	s, server := newRetryServer(t, failFirst(100))
+	policy := testRetryPolicy()
+	policy.InitialBackoff = time.Minute
+	policy.MaxBackoff = time.Minute
+	s.SetRetryPolicy(policy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := s.ejecutaPeticion(ctx, http.MethodGet, "/api/index", "", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, se espera %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("la cancelacion tardo %s", elapsed)
+	}
+	if server.attempts() != 1 {
+		t.Errorf("intentos = %d, se espera 1", server.attempts())
+	}
+	if stats := s.RetryStats(); stats.Failures != 1 {
+		t.Errorf("RetryStats = %+v", stats)
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Tipos de busqueda del API nativo de ZincSearch (/api/{indice}/_search)
+const (
+	SearchMatchAll    string = "matchall"
+	SearchMatch       string = "match"
+	SearchMatchPhrase string = "matchphrase"
+	SearchQueryString string = "querystring"
+	SearchTerm        string = "term"
+	SearchDateRange   string = "daterange"
+	SearchPrefix      string = "prefix"
+	SearchWildcard    string = "wildcard"
+	SearchFuzzy       string = "fuzzy"
+)
+
+// Peticion de busqueda del API nativo de ZincSearch
+type SearchRequest struct {
+	SearchType string      `json:"search_type"`
+	Query      SearchQuery `json:"query"`
+	//campos de ordenamiento, prefijo "-" para orden descendente. Ej. "-@timestamp"
+	SortFields []string `json:"sort_fields,omitempty"`
+	From       int      `json:"from"`
+	//cantidad maxima de resultados (size)
+	Size int `json:"max_results,omitempty"`
+	//campos a incluir en _source, vacio retorna todos
+	Source    []string         `json:"_source,omitempty"`
+	Highlight *SearchHighlight `json:"highlight,omitempty"`
+}
+
+type SearchQuery struct {
+	Term string `json:"term,omitempty"`
+	//campo donde se busca, vacio busca en _all
+	Field     string    `json:"field,omitempty"`
+	StartTime time.Time `json:"start_time,omitempty"`
+	EndTime   time.Time `json:"end_time,omitempty"`
+	Boost     int       `json:"boost,omitempty"`
+}
+
+// las fechas vacias se omiten, ZincSearch no acepta la fecha cero
+func (q SearchQuery) MarshalJSON() ([]byte, error) {
+	type searchQuery struct {
+		Term      string     `json:"term,omitempty"`
+		Field     string     `json:"field,omitempty"`
+		StartTime *time.Time `json:"start_time,omitempty"`
+		EndTime   *time.Time `json:"end_time,omitempty"`
+		Boost     int        `json:"boost,omitempty"`
+	}
+	sq := searchQuery{Term: q.Term, Field: q.Field, Boost: q.Boost}
+	if !q.StartTime.IsZero() {
+		sq.StartTime = &q.StartTime
+	}
+	if !q.EndTime.IsZero() {
+		sq.EndTime = &q.EndTime
+	}
+	return json.Marshal(sq)
+}
+
+// Configuracion de resaltado de coincidencias
+type SearchHighlight struct {
+	PreTags  []string                   `json:"pre_tags,omitempty"`
+	PostTags []string                   `json:"post_tags,omitempty"`
+	Fields   map[string]SearchHighlight `json:"fields,omitempty"`
+}
+
+// Resultado de busqueda
+type SearchResult struct {
+	Took     int64      `json:"took"`
+	TimedOut bool       `json:"timed_out"`
+	MaxScore float64    `json:"max_score"`
+	Hits     SearchHits `json:"hits"`
+}
+
+type SearchHits struct {
+	Total    SearchTotal `json:"total"`
+	MaxScore float64     `json:"max_score"`
+	Hits     []Hit       `json:"hits"`
+}
+
+type SearchTotal struct {
+	Value int64 `json:"value"`
+}
+
+// Documento encontrado
+type Hit struct {
+	Index     string    `json:"_index"`
+	Type      string    `json:"_type"`
+	ID        string    `json:"_id"`
+	Score     float64   `json:"_score"`
+	Timestamp time.Time `json:"@timestamp"`
+	//documento original, se decodifica con Decode
+	Source    json.RawMessage     `json:"_source"`
+	Highlight map[string][]string `json:"highlight,omitempty"`
+}
+
+// Decodifica el documento original en la estructura indicada
+func (h *Hit) Decode(v interface{}) error {
+	return json.Unmarshal(h.Source, v)
+}
+
+// Ejecuta busqueda sobre un indice
+func (s *ZincSearch) Search(ctx context.Context, indexName string, query SearchRequest) (result *SearchResult, err error) {
+	resource := "/api/" + indexName + "/_search"
+
+	//busqueda sin tipo indicado retorna todos los documentos
+	if query.SearchType == "" {
+		query.SearchType = SearchMatchAll
+	}
+
+	jsonBody, err := json.Marshal(query)
+	if err != nil {
+		return result, err
+	}
+
+	//ejecuta peticion
+	body, err := s.ejecutaPeticion(ctx, http.MethodPost, resource, "", bytes.NewReader(jsonBody))
+	if err != nil {
+		return result, indexNotFound(err)
+	}
+
+	//retorna respuesta
+	result = &SearchResult{}
+	err = decodeResponse(http.MethodPost, resource, body, result)
+	return result, err
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"compress/gzip"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"zincsearch.com/mailindex/api/helpers"
+)
+
+// variables de ambiente para configuracion del cliente HTTP
+const ZincSearchTimeout string = "ZINC_SERVER_TIMEOUT"
+const ZincSearchMaxIdleConns string = "ZINC_SERVER_MAX_IDLE_CONNS"
+const ZincSearchMaxIdleConnsPerHost string = "ZINC_SERVER_MAX_IDLE_CONNS_PER_HOST"
+const ZincSearchMaxConnsPerHost string = "ZINC_SERVER_MAX_CONNS_PER_HOST"
+const ZincSearchTLSCAFile string = "ZINC_SERVER_TLS_CA_FILE"
+const ZincSearchTLSCertFile string = "ZINC_SERVER_TLS_CERT_FILE"
+const ZincSearchTLSKeyFile string = "ZINC_SERVER_TLS_KEY_FILE"
+const ZincSearchTLSInsecure string = "ZINC_SERVER_TLS_INSECURE_SKIP_VERIFY"
+const ZincSearchProxy string = "ZINC_SERVER_PROXY"
+const ZincSearchGzip string = "ZINC_SERVER_GZIP"
+const ZincSearchGzipLevel string = "ZINC_SERVER_GZIP_LEVEL"
+const ZincSearchGzipMinSize string = "ZINC_SERVER_GZIP_MIN_SIZE"
+
+const defaultTimeout time.Duration = 20 * time.Second
+const defaultMaxIdleConns int = 100
+const defaultMaxIdleConnsPerHost int = 10
+const defaultIdleConnTimeout time.Duration = 90 * time.Second
+const defaultGzipMinSize int = 1024
+
+// Configuracion del cliente HTTP compartido por todas las peticiones de ZincSearch
+type TransportConfig struct {
+	//tiempo maximo por peticion, 0 indica sin limite (solo se cancela por contexto)
+	Timeout time.Duration
+
+	//pool de conexiones
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int //0 indica sin limite
+	IdleConnTimeout     time.Duration
+
+	//TLS: CA propia, certificado de cliente y omision de verificacion (solo desarrollo)
+	TLSCAFile             string
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSInsecureSkipVerify bool
+
+	//URL del proxy, vacio utiliza HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+	Proxy string
+
+	//compresion gzip del cuerpo de las peticiones (Content-Encoding: gzip),
+	//requiere que el servidor o proxy la soporte. Las respuestas comprimidas
+	//siempre se aceptan y se descomprimen de forma transparente
+	Gzip bool
+	//nivel de compresion de compress/gzip, -1 (por defecto) a 9
+	GzipLevel int
+	//cuerpos menores a este tamaño, en bytes, se envian sin comprimir
+	GzipMinSize int
+}
+
+// Configuracion por defecto del cliente HTTP
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		Timeout:             defaultTimeout,
+		MaxIdleConns:        defaultMaxIdleConns,
+		MaxIdleConnsPerHost: defaultMaxIdleConnsPerHost,
+		IdleConnTimeout:     defaultIdleConnTimeout,
+		GzipLevel:           gzip.DefaultCompression,
+		GzipMinSize:         defaultGzipMinSize,
+	}
+}
+
+// Obtiene configuracion del cliente HTTP de variables de ambiente,
+// los valores no definidos conservan su valor por defecto
+func TransportConfigFromEnv() (cfg TransportConfig, err error) {
+	cfg = DefaultTransportConfig()
+
+	if cfg.Timeout, err = helpers.GetEnvDuration(ZincSearchTimeout, cfg.Timeout); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxIdleConns, err = helpers.GetEnvInt(ZincSearchMaxIdleConns, cfg.MaxIdleConns); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxIdleConnsPerHost, err = helpers.GetEnvInt(ZincSearchMaxIdleConnsPerHost, cfg.MaxIdleConnsPerHost); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxConnsPerHost, err = helpers.GetEnvInt(ZincSearchMaxConnsPerHost, cfg.MaxConnsPerHost); err != nil {
+		return cfg, err
+	}
+
+	cfg.TLSCAFile = os.Getenv(ZincSearchTLSCAFile)
+	cfg.TLSCertFile = os.Getenv(ZincSearchTLSCertFile)
+	cfg.TLSKeyFile = os.Getenv(ZincSearchTLSKeyFile)
+	cfg.TLSInsecureSkipVerify = helpers.GetEnvBool(ZincSearchTLSInsecure)
+	cfg.Proxy = os.Getenv(ZincSearchProxy)
+
+	cfg.Gzip = helpers.GetEnvBool(ZincSearchGzip)
+	if txt := os.Getenv(ZincSearchGzipLevel); txt != "" {
+		level, err := strconv.Atoi(txt)
+		if err != nil {
+			return cfg, fmt.Errorf("el valor definido para %s debe ser numerico. Valor recibido: %q", ZincSearchGzipLevel, txt)
+		}
+		cfg.GzipLevel = level
+	}
+	if cfg.GzipMinSize, err = helpers.GetEnvInt(ZincSearchGzipMinSize, cfg.GzipMinSize); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// Reemplaza el cliente HTTP compartido utilizando la configuracion indicada
+func (s *ZincSearch) SetTransport(cfg TransportConfig) error {
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.GzipLevel < gzip.HuffmanOnly || cfg.GzipLevel > gzip.BestCompression {
+		return fmt.Errorf("nivel de compresion invalido: %d", cfg.GzipLevel)
+	}
+	s.client = client
+	s.compression = compression{enabled: cfg.Gzip, level: cfg.GzipLevel, minSize: cfg.GzipMinSize}
+	return nil
+}
+
+// obtiene cliente HTTP compartido, si no se ha configurado utiliza valores por defecto
+func (s *ZincSearch) httpClient() *http.Client {
+	if s.client == nil {
+		return defaultClient
+	}
+	return s.client
+}
+
+// cliente utilizado cuando no se invoca Inicia ni SetTransport
+var defaultClient, _ = newHTTPClient(DefaultTransportConfig())
+
+func newHTTPClient(cfg TransportConfig) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		proxyUrl, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("proxy invalido %q: %w", cfg.Proxy, err)
+		}
+		proxy = http.ProxyURL(proxyUrl)
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       tlsConfig,
+		//agrega Accept-Encoding: gzip y descomprime la respuesta
+		DisableCompression: false,
+	}
+
+	return &http.Client{Transport: transport, Timeout: cfg.Timeout}, nil
+}
+
+func newTLSConfig(cfg TransportConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.TLSInsecureSkipVerify}
+
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("no se pudo leer CA %q: %w", cfg.TLSCAFile, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("el archivo %q no contiene certificados PEM", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("no se pudo cargar certificado de cliente: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -1,13 +1,19 @@
 package service
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httputil"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
+
+	"zincsearch.com/mailindex/api/helpers"
 )
 
 const USUARIO string = "ZINC_FIRST_ADMIN_USER"
@@ -29,6 +35,18 @@ type ZincSearch struct {
 	host     string
 	port     string
 	https    bool
+
+	//cliente HTTP compartido (pool de conexiones) entre todas las peticiones
+	client *http.Client
+	//compresion gzip del cuerpo de las peticiones
+	compression compression
+
+	//politica de reintentos y contadores de intentos
+	retry    *RetryPolicy
+	requests atomic.Int64
+	attempts atomic.Int64
+	retries  atomic.Int64
+	failures atomic.Int64
 }
 
 // inicializa configuracion para ejecucion de peticiones hace ZincSearch
@@ -65,9 +83,110 @@ func (s *ZincSearch) Inicia() {
 	var https string = os.Getenv(ZincSearchHttps)
 	s.https = (https == "1" || https == "s" || https == "S")
 
+	//configura cliente HTTP: timeout, pool de conexiones, TLS y proxy
+	transportConfig, err := TransportConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = s.SetTransport(transportConfig); err != nil {
+		log.Fatal("Error en configuracion del cliente HTTP: ", err)
+	}
+
+	//configura politica de reintentos
+	retryPolicy, err := RetryPolicyFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.SetRetryPolicy(retryPolicy)
+
 	s.initDebug()
 }
 
+// ejecuta peticion hacia ZincSearch y retorna el cuerpo de la respuesta.
+// Una respuesta distinta de 2xx se retorna como *APIError
+func (s *ZincSearch) ejecutaPeticion(ctx context.Context, method string, resource string, urlQuery string, body io.Reader) (result []byte, err error) {
+	//obtiene string del URL
+	url := helpers.GetUrl(s.https, s.host, s.port, resource, urlQuery)
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return result, err
+	}
+
+	//comprime cuerpo si esta habilitado
+	s.comprimePeticion(req)
+
+	return s.enviaPeticion(req, resource)
+}
+
+// ejecuta peticion cuyo cuerpo se genera con write mientras se envia, sin
+// mantenerlo completo en memoria. En cada reintento write se invoca de nuevo
+func (s *ZincSearch) ejecutaPeticionStream(ctx context.Context, method string, resource string, urlQuery string, write func(w io.Writer) error) (result []byte, err error) {
+	//obtiene string del URL
+	url := helpers.GetUrl(s.https, s.host, s.port, resource, urlQuery)
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return result, err
+	}
+
+	//longitud desconocida, se envia con Transfer-Encoding: chunked
+	if err = s.asignaCuerpoStream(req, write); err != nil {
+		return result, err
+	}
+
+	return s.enviaPeticion(req, resource)
+}
+
+func (s *ZincSearch) enviaPeticion(req *http.Request, resource string) (result []byte, err error) {
+	req.Header.Add("Content-Type", "application/json")
+
+	s.debugReq(req)
+
+	//agrega credenciales para autenticacion
+	helpers.AddBasicAuth(req, s.usuario, s.password)
+	//ejecuta peticion, con reintentos
+	response, err := s.do(req)
+	if err != nil {
+		return result, fmt.Errorf("zincsearch: %s %s: %w", req.Method, resource, err)
+	}
+	defer response.Body.Close()
+
+	s.debugRes(response)
+
+	//obtiene resultado
+	result, err = helpers.GetResponseBytes(response)
+	if err != nil {
+		return result, fmt.Errorf("zincsearch: %s %s: %w", req.Method, resource, err)
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, newAPIError(req.Method, resource, response.StatusCode, result)
+	}
+
+	return result, nil
+}
+
+// tamaño del buffer entre el generador del cuerpo y la conexion
+const streamBufferSize int = 64 * 1024
+
+// crea cuerpo de peticion conectado por un pipe a la funcion que lo genera;
+// si la peticion termina antes de leerlo completo, el pipe se cierra y write recibe error
+func streamBody(write func(w io.Writer) error) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		pr, pw := io.Pipe()
+		go func() {
+			bw := bufio.NewWriterSize(pw, streamBufferSize)
+			err := write(bw)
+			if err == nil {
+				err = bw.Flush()
+			}
+			pw.CloseWithError(err)
+		}()
+		return pr, nil
+	}
+}
+
 func (s *ZincSearch) initDebug() {
 	debugTxt := os.Getenv("ZINC_LOCAL_DEBUG_ENABLED")
 	debugEnabled = (debugTxt != "" && (strings.ToLower(debugTxt) == "true" || debugTxt == "1"))
@@ -85,7 +204,7 @@ func (s *ZincSearch) debugReq(request *http.Request) {
 	if err == nil {
 		fmt.Printf("%s\n\n", data)
 	} else {
-		log.Fatalf("%s\n\n", err)
+		log.Printf("%s\n\n", err)
 	}
 }
 
@@ -97,6 +216,6 @@ func (s *ZincSearch) debugRes(response *http.Response) {
 	if err == nil {
 		fmt.Printf("%s\n\n", data)
 	} else {
-		log.Fatalf("%s\n\n", err)
+		log.Printf("%s\n\n", err)
 	}
 }